@@ -0,0 +1,164 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const accessCacheTTL = 30 * time.Second
+
+type AccessCheck struct {
+	Verb        string
+	Group       string
+	Resource    string
+	Subresource string
+	Name        string
+}
+
+// AccessSubject identifies the user checked by SubjectAccessReview.
+type AccessSubject struct {
+	User   string
+	Groups []string
+}
+
+type AccessResult struct {
+	AccessCheck
+	Allowed bool
+	Reason  string
+}
+
+// CanI reports whether the identity of this client may perform the check in
+// the namespace, using SelfSubjectAccessReview.
+func (s *ClientImpl) CanI(ctx context.Context, namespace string, check AccessCheck) (bool, error) {
+	result, err := s.checkAccess(ctx, namespace, nil, check)
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
+
+// CanUser reports whether the given user may perform the check in the
+// namespace, using SubjectAccessReview.
+func (s *ClientImpl) CanUser(ctx context.Context, namespace string, subject AccessSubject, check AccessCheck) (bool, error) {
+	result, err := s.checkAccess(ctx, namespace, &subject, check)
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
+
+// PermissionsMatrix evaluates every check in the namespace for subject, or for
+// this client's identity when subject is nil. Results are cached briefly.
+func (s *ClientImpl) PermissionsMatrix(ctx context.Context, namespace string, subject *AccessSubject, checks []AccessCheck) ([]AccessResult, error) {
+	r := make([]AccessResult, 0, len(checks))
+	for _, check := range checks {
+		result, err := s.checkAccess(ctx, namespace, subject, check)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, result)
+	}
+	return r, nil
+}
+
+func (s *ClientImpl) checkAccess(ctx context.Context, namespace string, subject *AccessSubject, check AccessCheck) (AccessResult, error) {
+	key := accessCacheKey(namespace, subject, check)
+	if result, ok := s.accessCache.get(key); ok {
+		return result, nil
+	}
+
+	attrs := &authorizationv1.ResourceAttributes{
+		Namespace:   namespace,
+		Verb:        check.Verb,
+		Group:       check.Group,
+		Resource:    check.Resource,
+		Subresource: check.Subresource,
+		Name:        check.Name,
+	}
+
+	var status authorizationv1.SubjectAccessReviewStatus
+	if subject == nil {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attrs},
+		}
+		created, err := s.clients.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return AccessResult{}, err
+		}
+		status = created.Status
+	} else {
+		review := &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: attrs,
+				User:               subject.User,
+				Groups:             subject.Groups,
+			},
+		}
+		created, err := s.clients.AuthorizationV1().SubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return AccessResult{}, err
+		}
+		status = created.Status
+	}
+
+	if status.EvaluationError != "" && !status.Allowed && !status.Denied {
+		return AccessResult{}, fmt.Errorf("access review %s %s: %s", check.Verb, check.Resource, status.EvaluationError)
+	}
+	result := AccessResult{AccessCheck: check, Allowed: status.Allowed, Reason: status.Reason}
+	s.accessCache.put(key, result)
+	return result, nil
+}
+
+func accessCacheKey(namespace string, subject *AccessSubject, check AccessCheck) string {
+	who := ""
+	if subject != nil {
+		who = subject.User + "|" + strings.Join(subject.Groups, ",")
+	}
+	return strings.Join([]string{who, namespace, check.Verb, check.Group, check.Resource, check.Subresource, check.Name}, "/")
+}
+
+type accessCacheEntry struct {
+	result  AccessResult
+	expires time.Time
+}
+
+type accessCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]accessCacheEntry
+}
+
+func newAccessCache(ttl time.Duration) *accessCache {
+	return &accessCache{ttl: ttl, entries: make(map[string]accessCacheEntry)}
+}
+
+func (s *accessCache) get(key string) (AccessResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		delete(s.entries, key)
+		return AccessResult{}, false
+	}
+	return entry.result, true
+}
+
+func (s *accessCache) put(key string, result AccessResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range s.entries {
+		if now.After(entry.expires) {
+			delete(s.entries, k)
+		}
+	}
+	s.entries[key] = accessCacheEntry{result: result, expires: now.Add(s.ttl)}
+}
@@ -0,0 +1,186 @@
+package k8sclient
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+var capacityResources = []corev1.ResourceName{
+	corev1.ResourceCPU,
+	corev1.ResourceMemory,
+	corev1.ResourceEphemeralStorage,
+	corev1.ResourceName(LABEL_NVIDIA_GPU),
+}
+
+type ResourceAllocation struct {
+	Allocatable resource.Quantity `json:"allocatable"`
+	Requests    resource.Quantity `json:"requests"`
+	Limits      resource.Quantity `json:"limits"`
+	Usage       resource.Quantity `json:"usage"`
+
+	RequestRatio float64 `json:"requestRatio"` // requests / allocatable
+	LimitRatio   float64 `json:"limitRatio"`   // limits / allocatable, above 1 means overcommitted
+	UsageRatio   float64 `json:"usageRatio"`   // usage / allocatable
+}
+
+type NodeCapacity struct {
+	Name          string                                     `json:"name"`
+	Unschedulable bool                                       `json:"unschedulable"`
+	PodCount      int                                        `json:"podCount"`
+	Resources     map[corev1.ResourceName]ResourceAllocation `json:"resources"`
+}
+
+type CapacityReport struct {
+	Nodes []NodeCapacity                             `json:"nodes"`
+	Total map[corev1.ResourceName]ResourceAllocation `json:"total"`
+}
+
+// NodeCapacityReport reports allocatable, requested, limited and used CPU,
+// memory, ephemeral storage and GPU per node and for the whole cluster.
+// Usage is left empty when metrics is nil or metrics-server is unavailable.
+func (s *ClientImpl) NodeCapacityReport(ctx context.Context, metrics *MetricsImpl, selector string) (*CapacityReport, error) {
+	nodes, err := s.ListNode(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := s.listNonTerminatedPods(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]corev1.ResourceList)
+	if metrics != nil {
+		if nodeMetrics, err := metrics.ListNode(ctx, selector); err == nil {
+			for _, m := range nodeMetrics.Items {
+				usage[m.Name] = m.Usage
+			}
+		}
+	}
+
+	type podTotals struct {
+		count    int
+		requests corev1.ResourceList
+		limits   corev1.ResourceList
+	}
+	byNode := make(map[string]*podTotals)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		t, ok := byNode[pod.Spec.NodeName]
+		if !ok {
+			t = &podTotals{requests: corev1.ResourceList{}, limits: corev1.ResourceList{}}
+			byNode[pod.Spec.NodeName] = t
+		}
+		reqs, limits := podRequestsAndLimits(pod)
+		addResourceList(t.requests, reqs)
+		addResourceList(t.limits, limits)
+		t.count++
+	}
+
+	report := &CapacityReport{Total: make(map[corev1.ResourceName]ResourceAllocation)}
+	for _, node := range nodes.Items {
+		nc := NodeCapacity{
+			Name:          node.Name,
+			Unschedulable: node.Spec.Unschedulable,
+			Resources:     make(map[corev1.ResourceName]ResourceAllocation),
+		}
+		t := byNode[node.Name]
+		if t == nil {
+			t = &podTotals{}
+		}
+		nc.PodCount = t.count
+
+		for _, name := range capacityResources {
+			a := ResourceAllocation{
+				Allocatable: node.Status.Allocatable[name].DeepCopy(),
+				Requests:    t.requests[name].DeepCopy(),
+				Limits:      t.limits[name].DeepCopy(),
+				Usage:       usage[node.Name][name].DeepCopy(),
+			}
+			a.computeRatios()
+			nc.Resources[name] = a
+
+			total := report.Total[name]
+			total.Allocatable.Add(a.Allocatable)
+			total.Requests.Add(a.Requests)
+			total.Limits.Add(a.Limits)
+			total.Usage.Add(a.Usage)
+			report.Total[name] = total
+		}
+		report.Nodes = append(report.Nodes, nc)
+	}
+
+	for name, total := range report.Total {
+		total.computeRatios()
+		report.Total[name] = total
+	}
+	sort.Slice(report.Nodes, func(i, j int) bool { return report.Nodes[i].Name < report.Nodes[j].Name })
+
+	return report, nil
+}
+
+func (s *ResourceAllocation) computeRatios() {
+	allocatable := s.Allocatable.AsApproximateFloat64()
+	if allocatable == 0 {
+		return
+	}
+	s.RequestRatio = s.Requests.AsApproximateFloat64() / allocatable
+	s.LimitRatio = s.Limits.AsApproximateFloat64() / allocatable
+	s.UsageRatio = s.Usage.AsApproximateFloat64() / allocatable
+}
+
+func (s *ClientImpl) listNonTerminatedPods(ctx context.Context, namespace string) (*corev1.PodList, error) {
+	selector := fields.AndSelectors(
+		fields.OneTermNotEqualSelector("status.phase", string(corev1.PodSucceeded)),
+		fields.OneTermNotEqualSelector("status.phase", string(corev1.PodFailed)),
+	)
+	opts := metav1.ListOptions{FieldSelector: selector.String()}
+	return s.clients.CoreV1().Pods(namespace).List(ctx, opts)
+}
+
+// podRequestsAndLimits computes the effective pod requests and limits: the sum
+// over app containers, raised to the largest init container, plus overhead.
+func podRequestsAndLimits(pod *corev1.Pod) (corev1.ResourceList, corev1.ResourceList) {
+	reqs, limits := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		addResourceList(reqs, container.Resources.Requests)
+		addResourceList(limits, container.Resources.Limits)
+	}
+	for _, container := range pod.Spec.InitContainers {
+		maxResourceList(reqs, container.Resources.Requests)
+		maxResourceList(limits, container.Resources.Limits)
+	}
+	if pod.Spec.Overhead != nil {
+		addResourceList(reqs, pod.Spec.Overhead)
+		for name, quantity := range pod.Spec.Overhead {
+			if value, ok := limits[name]; ok {
+				value.Add(quantity)
+				limits[name] = value
+			}
+		}
+	}
+	return reqs, limits
+}
+
+func addResourceList(list corev1.ResourceList, add corev1.ResourceList) {
+	for name, quantity := range add {
+		if value, ok := list[name]; ok {
+			value.Add(quantity)
+			list[name] = value
+		} else {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func maxResourceList(list corev1.ResourceList, other corev1.ResourceList) {
+	for name, quantity := range other {
+		if value, ok := list[name]; !ok || quantity.Cmp(value) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"log"
 	"time"
 
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
@@ -23,8 +25,12 @@ import (
 )
 
 type ClientImpl struct {
-	clients  *kubernetes.Clientset
-	apiSpecs ApiSpecs
+	clients       *kubernetes.Clientset
+	extClients    *apiextensionsclientset.Clientset
+	config        *rest.Config
+	apiSpecs      ApiSpecs
+	accessCache   *accessCache
+	eventRecorder *eventRecorder
 }
 
 func NewK8sClient(config *K8sClusterConfig) *ClientImpl {
@@ -34,7 +40,12 @@ func NewK8sClient(config *K8sClusterConfig) *ClientImpl {
 		log.Fatalf("load fail kubeclient %s", err.Error())
 	}
 
-	return &ClientImpl{clients: client}
+	extClient, err := apiextensionsclientset.NewForConfig(config.config)
+	if err != nil {
+		log.Fatalf("load fail apiextensions client %s", err.Error())
+	}
+
+	return &ClientImpl{clients: client, extClients: extClient, config: config.config, accessCache: newAccessCache(accessCacheTTL), eventRecorder: &eventRecorder{}}
 }
 
 func (s *ClientImpl) ApiSpecs() ApiSpecs {
@@ -106,7 +117,13 @@ func (s *ClientImpl) DeleteNamespace(ctx context.Context, name string) error {
 	//metav1.NewRVDeletionPrecondition(rv)
 	//metav1.NewUIDPreconditions()
 	opt := metav1.DeleteOptions{} // == metav1.NewDeleteOptions(0)
-	return s.clients.CoreV1().Namespaces().Delete(ctx, name, opt)
+	if err := s.clients.CoreV1().Namespaces().Delete(ctx, name, opt); err != nil {
+		return err
+	}
+
+	ref := &corev1.ObjectReference{Kind: TYPEMETA_KIND_NAMESPACE, APIVersion: TYPEMETA_APIVERSION_V1, Name: name}
+	s.RecordNormalEvent(ref, EVENT_REASON_DELETED, EVENT_ACTION_DELETE, "Namespace %s deleted by %s", name, FIELD_MANAGER)
+	return nil
 }
 
 func (s *ClientImpl) WatchNamespaces(ctx context.Context, selector string) (watch.Interface, error) {
@@ -420,7 +437,12 @@ func (s *ClientImpl) ApplyResourceQuota(ctx context.Context, namespace string, n
 
 	opt := metav1.ApplyOptions{FieldManager: FIELD_MANAGER}
 
-	return s.clients.CoreV1().ResourceQuotas(namespace).Apply(ctx, &config, opt)
+	quota, err := s.clients.CoreV1().ResourceQuotas(namespace).Apply(ctx, &config, opt)
+	if err != nil {
+		return nil, err
+	}
+	s.RecordNormalEvent(quota, EVENT_REASON_QUOTA_APPLIED, EVENT_ACTION_APPLY, "ResourceQuota %s applied by %s", name, FIELD_MANAGER)
+	return quota, nil
 }
 
 func (s *ClientImpl) ListResourceQuota(ctx context.Context, namespace string, selector string) (*corev1.ResourceQuotaList, error) {
@@ -511,7 +533,12 @@ func (s *ClientImpl) RestartDeployment(ctx context.Context, namespace string, na
 	opts := metav1.PatchOptions{FieldManager: FIELD_MANAGER}
 
 	data := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"builder.aiblab.co.kr/restartedAt":"%s"}}}}}`, time.Now().String())
-	return s.clients.AppsV1().Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(data), opts)
+	deployment, err := s.clients.AppsV1().Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(data), opts)
+	if err != nil {
+		return nil, err
+	}
+	s.RecordNormalEvent(deployment, EVENT_REASON_RESTARTED, EVENT_ACTION_RESTART, "Deployment %s restarted by %s", name, FIELD_MANAGER)
+	return deployment, nil
 }
 
 func (s *ClientImpl) ListDaemonSet(ctx context.Context, namespace string, selector string) (*appsv1.DaemonSetList, error) {
@@ -547,7 +574,12 @@ func (s *ClientImpl) RestartDaemonSet(ctx context.Context, namespace string, nam
 	opts := metav1.PatchOptions{FieldManager: FIELD_MANAGER}
 
 	data := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"builder.aiblab.co.kr/restartedAt":"%s"}}}}}`, time.Now().String())
-	return s.clients.AppsV1().DaemonSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(data), opts)
+	daemonSet, err := s.clients.AppsV1().DaemonSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(data), opts)
+	if err != nil {
+		return nil, err
+	}
+	s.RecordNormalEvent(daemonSet, EVENT_REASON_RESTARTED, EVENT_ACTION_RESTART, "DaemonSet %s restarted by %s", name, FIELD_MANAGER)
+	return daemonSet, nil
 }
 
 func (s *ClientImpl) ListStatefulSet(ctx context.Context, namespace string, selector string) (*appsv1.StatefulSetList, error) {
@@ -583,7 +615,12 @@ func (s *ClientImpl) RestartStatefulSet(ctx context.Context, namespace string, n
 	opts := metav1.PatchOptions{FieldManager: FIELD_MANAGER}
 
 	data := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"builder.aiblab.co.kr/restartedAt":"%s"}}}}}`, time.Now().String())
-	return s.clients.AppsV1().StatefulSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(data), opts)
+	statefulSet, err := s.clients.AppsV1().StatefulSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(data), opts)
+	if err != nil {
+		return nil, err
+	}
+	s.RecordNormalEvent(statefulSet, EVENT_REASON_RESTARTED, EVENT_ACTION_RESTART, "StatefulSet %s restarted by %s", name, FIELD_MANAGER)
+	return statefulSet, nil
 }
 
 func (s *ClientImpl) ListReplicaSet(ctx context.Context, namespace string, selector string) (*appsv1.ReplicaSetList, error) {
@@ -676,7 +713,16 @@ func (s *ClientImpl) DeleteCronJobs(ctx context.Context, namespace string, selec
 	return s.clients.BatchV1().CronJobs(namespace).DeleteCollection(ctx, deleteOpt, selectOpt)
 }
 
-func (s *ClientImpl) GetStorageClass(ctx context.Context, namespace string, name string) (*storagev1.StorageClass, error) {
+func (s *ClientImpl) ListStorageClass(ctx context.Context, selector string) (*storagev1.StorageClassList, error) {
+	opts := metav1.ListOptions{}
+	if selector != "" {
+		opts.LabelSelector = selector
+	}
+
+	return s.clients.StorageV1().StorageClasses().List(ctx, opts)
+}
+
+func (s *ClientImpl) GetStorageClass(ctx context.Context, name string) (*storagev1.StorageClass, error) {
 	opt := metav1.GetOptions{}
 
 	return s.clients.StorageV1().StorageClasses().Get(ctx, name, opt)
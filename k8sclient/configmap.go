@@ -0,0 +1,190 @@
+package k8sclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	configv1 "k8s.io/client-go/applyconfigurations/core/v1"
+	configmetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+const configMapMaxSize = 1024 * 1024
+
+// ConfigMapData collects ConfigMap content. Values that are not valid UTF-8
+// are kept in BinaryData.
+type ConfigMapData struct {
+	Data       map[string]string
+	BinaryData map[string][]byte
+}
+
+type ConfigMapOptions struct {
+	Labels     K8sLabels
+	Immutable  bool
+	HashSuffix bool // append a content hash to the name so every change creates a new ConfigMap
+}
+
+func NewConfigMapData() *ConfigMapData {
+	return &ConfigMapData{Data: make(map[string]string), BinaryData: make(map[string][]byte)}
+}
+
+func (s *ConfigMapData) Put(key string, value []byte) error {
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+		return fmt.Errorf("invalid key %q: %s", key, strings.Join(errs, "; "))
+	}
+	if _, ok := s.Data[key]; ok {
+		return fmt.Errorf("duplicate key %s", key)
+	}
+	if _, ok := s.BinaryData[key]; ok {
+		return fmt.Errorf("duplicate key %s", key)
+	}
+
+	if utf8.Valid(value) {
+		s.Data[key] = string(value)
+	} else {
+		s.BinaryData[key] = value
+	}
+	return nil
+}
+
+// FromFile is the equivalent of `kubectl create configmap --from-file`.
+func (s *ConfigMapData) FromFile(sources ...string) error {
+	data, err := DataFromFiles(sources...)
+	if err != nil {
+		return err
+	}
+	return s.putAll(data)
+}
+
+// FromLiteral is the equivalent of `--from-literal`, each literal is key=value.
+func (s *ConfigMapData) FromLiteral(literals ...string) error {
+	for _, literal := range literals {
+		i := strings.Index(literal, "=")
+		if i <= 0 {
+			return fmt.Errorf("invalid literal %q, expected key=value", literal)
+		}
+		if err := s.Put(literal[:i], []byte(literal[i+1:])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FromEnvFile is the equivalent of `--from-env-file`. Lines are KEY=VALUE,
+// blank lines and lines starting with # are ignored, and a bare KEY takes its
+// value from the current environment.
+func (s *ConfigMapData) FromEnvFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimLeftFunc(scanner.Text(), unicode.IsSpace)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value := line, ""
+		if i := strings.Index(line, "="); i >= 0 {
+			key, value = line[:i], line[i+1:]
+		} else if env, ok := os.LookupEnv(line); ok {
+			value = env
+		} else {
+			continue
+		}
+		if errs := validation.IsEnvVarName(key); len(errs) > 0 {
+			return fmt.Errorf("%s:%d invalid key %q: %s", path, n, key, strings.Join(errs, "; "))
+		}
+		if err := s.Put(key, []byte(value)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *ConfigMapData) Size() int {
+	size := 0
+	for k, v := range s.Data {
+		size += len(k) + len(v)
+	}
+	for k, v := range s.BinaryData {
+		size += len(k) + len(v)
+	}
+	return size
+}
+
+// Hash returns a short hash of the content, stable across key order.
+func (s *ConfigMapData) Hash() string {
+	keys := make([]string, 0, len(s.Data)+len(s.BinaryData))
+	for k := range s.Data {
+		keys = append(keys, k)
+	}
+	for k := range s.BinaryData {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		if v, ok := s.Data[k]; ok {
+			h.Write([]byte(v))
+		} else {
+			h.Write(s.BinaryData[k])
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:10]
+}
+
+func (s *ConfigMapData) putAll(data map[string][]byte) error {
+	for k, v := range data {
+		if err := s.Put(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyConfigMap applies the ConfigMap after checking the 1 MiB size limit.
+// With HashSuffix the content hash is appended to name, and the returned
+// ConfigMap carries the final name.
+func (s *ClientImpl) ApplyConfigMap(ctx context.Context, namespace string, name string, data *ConfigMapData, opts ConfigMapOptions) (*corev1.ConfigMap, error) {
+	if size := data.Size(); size > configMapMaxSize {
+		return nil, fmt.Errorf("configmap %s is %d bytes, exceeds the %d byte limit", name, size, configMapMaxSize)
+	}
+	if opts.HashSuffix {
+		name = fmt.Sprintf("%s-%s", name, data.Hash())
+	}
+
+	kind := TYPEMETA_KIND_CONFIGMAP
+	ver := TYPEMETA_APIVERSION_V1
+
+	config := configv1.ConfigMapApplyConfiguration{
+		TypeMetaApplyConfiguration:   configmetav1.TypeMetaApplyConfiguration{Kind: &kind, APIVersion: &ver},
+		ObjectMetaApplyConfiguration: &configmetav1.ObjectMetaApplyConfiguration{Namespace: &namespace, Name: &name, Labels: opts.Labels},
+		Data:                         data.Data,
+		BinaryData:                   data.BinaryData,
+	}
+	if opts.Immutable {
+		config.Immutable = &opts.Immutable
+	}
+	opt := metav1.ApplyOptions{FieldManager: FIELD_MANAGER}
+
+	return s.clients.CoreV1().ConfigMaps(namespace).Apply(ctx, &config, opt)
+}
@@ -10,6 +10,8 @@ const (
 	LABEL_IDPP2_USERNAME      = "aiblab.co.kr/username"
 	LABEL_JUPYTERUSERNAME     = "hub.jupyter.org/username"
 	LABEL_NVIDIA_GPU          = "nvidia.com/gpu"
+	LABEL_NVIDIA_GPU_PRODUCT  = "nvidia.com/gpu.product"
+	LABEL_NAMESPACE_NAME      = "kubernetes.io/metadata.name"
 )
 
 const (
@@ -22,16 +24,27 @@ const (
 )
 
 const (
-	TYPEMETA_KIND_NAMESPACE     = "Namespace"
-	TYPEMETA_KIND_RESOURCEQUOTA = "ResourceQuota"
-	TYPEMETA_KIND_POD           = "Pod"
-	TYPEMETA_KIND_SERVICE       = "Service"
-	TYPEMETA_KIND_DEPLOYMENT    = "Deployment"
-	TYPEMETA_KIND_JOB           = "Job"
+	TYPEMETA_KIND_NAMESPACE      = "Namespace"
+	TYPEMETA_KIND_RESOURCEQUOTA  = "ResourceQuota"
+	TYPEMETA_KIND_POD            = "Pod"
+	TYPEMETA_KIND_SERVICE        = "Service"
+	TYPEMETA_KIND_DEPLOYMENT     = "Deployment"
+	TYPEMETA_KIND_JOB            = "Job"
+	TYPEMETA_KIND_CRONJOB        = "CronJob"
+	TYPEMETA_KIND_SECRET         = "Secret"
+	TYPEMETA_KIND_CONFIGMAP      = "ConfigMap"
+	TYPEMETA_KIND_STATEFULSET    = "StatefulSet"
+	TYPEMETA_KIND_PV             = "PersistentVolume"
+	TYPEMETA_KIND_PVC            = "PersistentVolumeClaim"
+	TYPEMETA_KIND_VOLUMESNAPSHOT = "VolumeSnapshot"
+	TYPEMETA_KIND_CRD            = "CustomResourceDefinition"
 )
 
 const (
-	TYPEMETA_APIVERSION_V1              = "v1"
-	TYPEMETA_APIVERSION_BATCH_V1        = "batch/v1"
-	TYPEMETA_APIVERSION_METRICS_V1BETA1 = "metrics.k8s.io/v1beta1"
+	TYPEMETA_APIVERSION_V1               = "v1"
+	TYPEMETA_APIVERSION_BATCH_V1         = "batch/v1"
+	TYPEMETA_APIVERSION_APPS_V1          = "apps/v1"
+	TYPEMETA_APIVERSION_METRICS_V1BETA1  = "metrics.k8s.io/v1beta1"
+	TYPEMETA_APIVERSION_SNAPSHOT_V1      = "snapshot.storage.k8s.io/v1"
+	TYPEMETA_APIVERSION_APIEXTENSIONS_V1 = "apiextensions.k8s.io/v1"
 )
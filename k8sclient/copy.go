@@ -0,0 +1,359 @@
+package k8sclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type CopyProgress struct {
+	Path       string // entry path relative to the copied root
+	Size       int64  // size of the entry, 0 for directories and symlinks
+	Written    int64  // bytes of the entry transferred so far
+	TotalBytes int64  // bytes transferred by the whole copy so far
+}
+
+type CopyOptions struct {
+	Container string
+	Progress  func(CopyProgress)
+}
+
+// CopyToPod copies a local file or directory to destPath inside the pod by
+// streaming a tar archive into `tar -x` over exec. A destPath ending in "/" is
+// treated as the parent directory and keeps the source base name.
+func (s *ClientImpl) CopyToPod(ctx context.Context, namespace string, name string, srcPath string, destPath string, opts CopyOptions) error {
+	if _, err := os.Lstat(srcPath); err != nil {
+		return err
+	}
+	if strings.HasSuffix(destPath, "/") {
+		destPath = path.Join(destPath, filepath.Base(srcPath))
+	}
+	destPath = path.Clean(destPath)
+
+	pr, pw := io.Pipe()
+	writeErr := make(chan error, 1)
+	go func() {
+		err := writeTar(pw, srcPath, path.Base(destPath), opts.Progress)
+		pw.CloseWithError(err)
+		writeErr <- err
+	}()
+
+	var stderr bytes.Buffer
+	err := s.ExecPod(ctx, namespace, name, ExecOptions{
+		Container: opts.Container,
+		Command:   []string{"tar", "-xmf", "-", "-C", path.Dir(destPath)},
+		Stdin:     pr,
+		Stdout:    io.Discard,
+		Stderr:    &stderr,
+	})
+	pr.CloseWithError(fmt.Errorf("copy to pod %s/%s aborted", namespace, name))
+
+	if werr := <-writeErr; werr != nil {
+		return fmt.Errorf("copy %s to pod %s/%s: %v", srcPath, namespace, name, werr)
+	}
+	if err != nil {
+		return fmt.Errorf("copy %s to pod %s/%s: %v %s", srcPath, namespace, name, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// CopyFromPod copies srcPath from the pod to the local destPath by running
+// `tar -c` over exec. Entries that would land outside destPath are refused,
+// and symlinks pointing outside destPath are skipped.
+func (s *ClientImpl) CopyFromPod(ctx context.Context, namespace string, name string, srcPath string, destPath string, opts CopyOptions) error {
+	srcPath = path.Clean(srcPath)
+	if len(destPath) > 0 && os.IsPathSeparator(destPath[len(destPath)-1]) {
+		destPath = filepath.Join(destPath, path.Base(srcPath))
+	}
+	destPath, err := filepath.Abs(destPath)
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	var stderr bytes.Buffer
+	execErr := make(chan error, 1)
+	go func() {
+		err := s.ExecPod(ctx, namespace, name, ExecOptions{
+			Container: opts.Container,
+			Command:   []string{"tar", "-cf", "-", "-C", path.Dir(srcPath), path.Base(srcPath)},
+			Stdout:    pw,
+			Stderr:    &stderr,
+		})
+		pw.CloseWithError(err)
+		execErr <- err
+	}()
+
+	err = readTar(pr, path.Base(srcPath), destPath, opts.Progress)
+	pr.CloseWithError(fmt.Errorf("copy from pod %s/%s aborted", namespace, name))
+
+	if eerr := <-execErr; eerr != nil {
+		return fmt.Errorf("copy %s from pod %s/%s: %v %s", srcPath, namespace, name, eerr, strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return fmt.Errorf("copy %s from pod %s/%s: %v", srcPath, namespace, name, err)
+	}
+	return nil
+}
+
+type progressReader struct {
+	r        io.Reader
+	progress CopyProgress
+	total    *int64
+	fn       func(CopyProgress)
+}
+
+func (s *progressReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if n > 0 {
+		s.progress.Written += int64(n)
+		*s.total += int64(n)
+		s.report()
+	}
+	return n, err
+}
+
+func (s *progressReader) report() {
+	if s.fn == nil {
+		return
+	}
+	s.progress.TotalBytes = *s.total
+	s.fn(s.progress)
+}
+
+func writeTar(w io.Writer, srcPath string, prefix string, fn func(CopyProgress)) error {
+	tw := tar.NewWriter(w)
+	var total int64
+
+	err := filepath.Walk(srcPath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcPath, p)
+		if err != nil {
+			return err
+		}
+		mode := info.Mode()
+		if !mode.IsRegular() && !mode.IsDir() && mode&os.ModeSymlink == 0 {
+			log.Printf("copy skip unsupported file %s", p)
+			return nil
+		}
+
+		link := ""
+		if mode&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(prefix, filepath.ToSlash(rel))
+		if mode.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		pr := &progressReader{progress: CopyProgress{Path: filepath.ToSlash(rel), Size: hdr.Size}, total: &total, fn: fn}
+		if !mode.IsRegular() {
+			pr.report()
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		pr.r = f
+		_, err = io.Copy(tw, pr)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+func readTar(r io.Reader, prefix string, destPath string, fn func(CopyProgress)) error {
+	tr := tar.NewReader(r)
+	var total int64
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(hdr.Name)
+		rel := ""
+		if name != prefix {
+			if !strings.HasPrefix(name, prefix+"/") {
+				return fmt.Errorf("unexpected archive entry %s", hdr.Name)
+			}
+			rel = strings.TrimPrefix(name, prefix+"/")
+		}
+		target := filepath.Join(destPath, filepath.FromSlash(rel))
+		if !withinDir(destPath, target) {
+			return fmt.Errorf("archive entry %s escapes %s", hdr.Name, destPath)
+		}
+		// an earlier symlink entry could redirect this one outside destPath
+		parent := filepath.Dir(target)
+		if hdr.Typeflag == tar.TypeDir {
+			parent = target
+		}
+		if err := checkNoSymlink(destPath, parent); err != nil {
+			return fmt.Errorf("archive entry %s: %v", hdr.Name, err)
+		}
+
+		pr := &progressReader{progress: CopyProgress{Path: rel, Size: hdr.Size}, total: &total, fn: fn}
+		perm := hdr.FileInfo().Mode().Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, perm); err != nil {
+				return err
+			}
+			pr.report()
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := removeSymlink(target); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+			if err != nil {
+				return err
+			}
+			pr.r = tr
+			_, err = io.Copy(f, pr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+			if err := os.Chmod(target, perm); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if filepath.IsAbs(hdr.Linkname) || !withinDir(destPath, filepath.Join(filepath.Dir(target), hdr.Linkname)) {
+				log.Printf("copy skip symlink %s -> %s outside %s", hdr.Name, hdr.Linkname, destPath)
+				continue
+			}
+			if err := checkLinkTarget(filepath.Dir(target), hdr.Linkname); err != nil {
+				log.Printf("copy skip symlink %s -> %s: %v", hdr.Name, hdr.Linkname, err)
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := removeSymlink(target); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+			pr.report()
+		default:
+			log.Printf("copy skip unsupported entry %s", hdr.Name)
+		}
+	}
+}
+
+func withinDir(dir string, target string) bool {
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkNoSymlink fails when a path component between dir and target is a
+// symlink, so nothing is created through a link.
+func checkNoSymlink(dir string, target string) error {
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	current := dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("path component %s is a symlink", current)
+		}
+	}
+	return nil
+}
+
+// checkLinkTarget rejects link targets the lexical check cannot vouch for:
+// ".." after a name, which could climb out of a directory that is or later
+// becomes a symlink, and existing symlinks along the way.
+func checkLinkTarget(linkDir string, linkname string) error {
+	current := linkDir
+	named := false
+	for _, part := range strings.Split(filepath.ToSlash(linkname), "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if named {
+				return fmt.Errorf("target climbs back out of a path component")
+			}
+			current = filepath.Dir(current)
+			continue
+		}
+		named = true
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("path component %s is a symlink", current)
+		}
+	}
+	return nil
+}
+
+func removeSymlink(target string) error {
+	info, err := os.Lstat(target)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return os.Remove(target)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package k8sclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	linkname string
+	body     string
+	typeflag byte
+}
+
+func buildTar(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Linkname: e.linkname, Typeflag: e.typeflag, Mode: 0644, Size: int64(len(e.body))}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestReadTar(t *testing.T) {
+	dest := t.TempDir()
+	archive := buildTar(t, []tarEntry{
+		{name: "p", typeflag: tar.TypeDir},
+		{name: "p/sub", typeflag: tar.TypeDir},
+		{name: "p/sub/file", body: "data", typeflag: tar.TypeReg},
+		{name: "p/link", linkname: "sub/file", typeflag: tar.TypeSymlink},
+	})
+
+	if err := readTar(archive, "p", dest, nil); err != nil {
+		t.Fatalf("readTar: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "data" {
+		t.Errorf("got %q, want %q", data, "data")
+	}
+}
+
+func TestReadTarSymlinkEscape(t *testing.T) {
+	root := t.TempDir()
+	dest := filepath.Join(root, "dest")
+	if err := os.Mkdir(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	archive := buildTar(t, []tarEntry{
+		{name: "p/x", linkname: ".", typeflag: tar.TypeSymlink},
+		{name: "p/l", linkname: "x/..", typeflag: tar.TypeSymlink},
+		{name: "p/l/evil", body: "evil", typeflag: tar.TypeReg},
+	})
+
+	// the escaping link is skipped, so evil lands in a plain directory l
+	if err := readTar(archive, "p", dest, nil); err != nil {
+		t.Fatalf("readTar: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(root, "evil")); !os.IsNotExist(err) {
+		t.Errorf("file escaped %s: %v", dest, err)
+	}
+	info, err := os.Lstat(filepath.Join(dest, "l"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("symlink l escaping %s was created", dest)
+	}
+}
+
+func TestReadTarWriteThroughSymlink(t *testing.T) {
+	dest := t.TempDir()
+	archive := buildTar(t, []tarEntry{
+		{name: "p/x", linkname: ".", typeflag: tar.TypeSymlink},
+		{name: "p/x/file", body: "data", typeflag: tar.TypeReg},
+	})
+
+	if err := readTar(archive, "p", dest, nil); err == nil {
+		t.Error("readTar accepted an entry written through a symlink")
+	}
+}
+
+func TestReadTarSymlinkChain(t *testing.T) {
+	root := t.TempDir()
+	dest := filepath.Join(root, "dest")
+	if err := os.Mkdir(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	archive := buildTar(t, []tarEntry{
+		{name: "p/x", linkname: ".", typeflag: tar.TypeSymlink},
+		{name: "p/l", linkname: "x/..", typeflag: tar.TypeSymlink},
+		{name: "p/m", linkname: "y/..", typeflag: tar.TypeSymlink},
+		{name: "p/y", linkname: ".", typeflag: tar.TypeSymlink},
+	})
+
+	if err := readTar(archive, "p", dest, nil); err != nil {
+		t.Fatalf("readTar: %v", err)
+	}
+	for _, name := range []string{"l", "m"} {
+		if _, err := os.Lstat(filepath.Join(dest, name)); !os.IsNotExist(err) {
+			t.Errorf("symlink %s escaping %s was created: %v", name, dest, err)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(dest, "x")); err != nil {
+		t.Errorf("symlink x inside %s was not created: %v", dest, err)
+	}
+}
+
+func TestReadTarDirThroughSymlink(t *testing.T) {
+	root := t.TempDir()
+	dest := filepath.Join(root, "dest")
+	if err := os.Mkdir(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	archive := buildTar(t, []tarEntry{
+		{name: "p/x", linkname: ".", typeflag: tar.TypeSymlink},
+		{name: "p/x", typeflag: tar.TypeDir},
+	})
+
+	if err := readTar(archive, "p", dest, nil); err == nil {
+		t.Error("readTar accepted a directory entry on top of a symlink")
+	}
+}
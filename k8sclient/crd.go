@@ -0,0 +1,210 @@
+package k8sclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	watch "k8s.io/apimachinery/pkg/watch"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+type CustomResourceDefinitionVersion struct {
+	Name       string
+	Served     bool
+	Storage    bool
+	Deprecated bool
+}
+
+type CustomResourceDefinitionInfo struct {
+	Name           string
+	Group          string
+	Kind           string
+	Plural         string
+	Scope          string
+	Versions       []CustomResourceDefinitionVersion
+	StoredVersions []string // versions that may still be persisted in etcd
+	Established    bool
+}
+
+// ApplyCustomResourceDefinition installs or upgrades the CRD with server-side
+// apply, waits until it is Established with its names accepted and refreshes
+// ApiSpecs so the new kind resolves right away. The apply is forced: this
+// service owns the CRDs it installs and takes over fields last set by other
+// managers, e.g. kubectl or a chart, instead of failing on a conflict.
+func (s *ClientImpl) ApplyCustomResourceDefinition(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) (*apiextensionsv1.CustomResourceDefinition, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(crd)
+	if err != nil {
+		return nil, err
+	}
+	obj["apiVersion"] = TYPEMETA_APIVERSION_APIEXTENSIONS_V1
+	obj["kind"] = TYPEMETA_KIND_CRD
+	delete(obj, "status")
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	force := true
+	opts := metav1.PatchOptions{FieldManager: FIELD_MANAGER, Force: &force}
+	if _, err := s.extClients.ApiextensionsV1().CustomResourceDefinitions().Patch(ctx, crd.Name, types.ApplyPatchType, data, opts); err != nil {
+		return nil, err
+	}
+
+	established, err := s.WaitCustomResourceDefinitionEstablished(ctx, crd.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refreshApiSpecs(ctx, established.Spec.Names.Kind); err != nil {
+		return nil, err
+	}
+	return established, nil
+}
+
+func (s *ClientImpl) ApplyCustomResourceDefinitionFromSpecs(ctx context.Context, spec ResourceSpecs) (*apiextensionsv1.CustomResourceDefinition, error) {
+	if kind := spec.GetKind(); kind != TYPEMETA_KIND_CRD {
+		return nil, fmt.Errorf("resource kind %s is not %s", kind, TYPEMETA_KIND_CRD)
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := json.Unmarshal(data, crd); err != nil {
+		return nil, err
+	}
+	return s.ApplyCustomResourceDefinition(ctx, crd)
+}
+
+// WaitCustomResourceDefinitionEstablished watches the CRD until it is
+// Established and its current names are accepted. A name conflict fails
+// right away.
+func (s *ClientImpl) WaitCustomResourceDefinitionEstablished(ctx context.Context, name string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	crd, err := s.GetCustomResourceDefinition(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := crdEstablished(crd); ok || err != nil {
+		return crd, err
+	}
+
+	opt := metav1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+		ResourceVersion: crd.ResourceVersion,
+	}
+	w, err := s.extClients.ApiextensionsV1().CustomResourceDefinitions().Watch(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := watchtools.UntilWithoutRetry(ctx, w, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("customresourcedefinition %s deleted", name)
+		}
+		crd, ok := event.Object.(*apiextensionsv1.CustomResourceDefinition)
+		if !ok {
+			return false, nil
+		}
+		return crdEstablished(crd)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event.Object.(*apiextensionsv1.CustomResourceDefinition), nil
+}
+
+func (s *ClientImpl) GetCustomResourceDefinition(ctx context.Context, name string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	opt := metav1.GetOptions{}
+	return s.extClients.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, name, opt)
+}
+
+func (s *ClientImpl) ListCustomResourceDefinition(ctx context.Context, selector string) (*apiextensionsv1.CustomResourceDefinitionList, error) {
+	opts := metav1.ListOptions{}
+	if selector != "" {
+		opts.LabelSelector = selector
+	}
+	return s.extClients.ApiextensionsV1().CustomResourceDefinitions().List(ctx, opts)
+}
+
+// ListInstalledCustomResourceDefinitions summarizes installed CRDs with their
+// served and storage versions.
+func (s *ClientImpl) ListInstalledCustomResourceDefinitions(ctx context.Context, selector string) ([]CustomResourceDefinitionInfo, error) {
+	list, err := s.ListCustomResourceDefinition(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	r := make([]CustomResourceDefinitionInfo, 0, len(list.Items))
+	for i := range list.Items {
+		crd := &list.Items[i]
+		info := CustomResourceDefinitionInfo{
+			Name:           crd.Name,
+			Group:          crd.Spec.Group,
+			Kind:           crd.Spec.Names.Kind,
+			Plural:         crd.Spec.Names.Plural,
+			Scope:          string(crd.Spec.Scope),
+			StoredVersions: crd.Status.StoredVersions,
+		}
+		info.Established, _ = crdEstablished(crd)
+		for _, version := range crd.Spec.Versions {
+			info.Versions = append(info.Versions, CustomResourceDefinitionVersion{
+				Name:       version.Name,
+				Served:     version.Served,
+				Storage:    version.Storage,
+				Deprecated: version.Deprecated,
+			})
+		}
+		r = append(r, info)
+	}
+	return r, nil
+}
+
+func (s *ClientImpl) DeleteCustomResourceDefinition(ctx context.Context, name string) error {
+	opt := metav1.DeleteOptions{} // == metav1.NewDeleteOptions(0)
+	if err := s.extClients.ApiextensionsV1().CustomResourceDefinitions().Delete(ctx, name, opt); err != nil {
+		return err
+	}
+	s.ResetApiSpecs()
+	return nil
+}
+
+// ResetApiSpecs drops the cached ApiSpecs so the next call reads discovery
+// again.
+func (s *ClientImpl) ResetApiSpecs() {
+	s.apiSpecs = nil
+}
+
+// refreshApiSpecs reloads ApiSpecs until discovery serves the kind.
+func (s *ClientImpl) refreshApiSpecs(ctx context.Context, kind string) error {
+	return wait.PollImmediateUntilWithContext(ctx, time.Second, func(ctx context.Context) (bool, error) {
+		s.ResetApiSpecs()
+		_, ok := s.ApiSpecs()[kind]
+		return ok, nil
+	})
+}
+
+func crdEstablished(crd *apiextensionsv1.CustomResourceDefinition) (bool, error) {
+	established, namesAccepted := false, false
+	for _, cond := range crd.Status.Conditions {
+		switch cond.Type {
+		case apiextensionsv1.Established:
+			established = cond.Status == apiextensionsv1.ConditionTrue
+		case apiextensionsv1.NamesAccepted:
+			if cond.Status == apiextensionsv1.ConditionFalse {
+				return false, fmt.Errorf("customresourcedefinition %s names not accepted: %s", crd.Name, cond.Message)
+			}
+			namesAccepted = cond.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+	// on upgrades the conditions stay true until the new names are processed
+	accepted := crd.Status.AcceptedNames
+	current := accepted.Kind == crd.Spec.Names.Kind && accepted.Plural == crd.Spec.Names.Plural
+	return established && namesAccepted && current, nil
+}
@@ -0,0 +1,148 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+const (
+	ANNOTATION_CRONJOB_INSTANTIATE = "cronjob.kubernetes.io/instantiate"
+	CRONJOB_INSTANTIATE_MANUAL     = "manual"
+)
+
+const (
+	JOB_STATUS_RUNNING   = "Running"
+	JOB_STATUS_SUCCEEDED = "Succeeded"
+	JOB_STATUS_FAILED    = "Failed"
+)
+
+type CronJobRun struct {
+	Name           string
+	Manual         bool   // created by TriggerCronJob rather than the schedule
+	Status         string // one of the JOB_STATUS_* values
+	Reason         string
+	Message        string
+	Succeeded      int32
+	Failed         int32
+	CreationTime   metav1.Time
+	StartTime      *metav1.Time
+	CompletionTime *metav1.Time
+}
+
+func (s *ClientImpl) SuspendCronJob(ctx context.Context, namespace string, name string) (*batchv1.CronJob, error) {
+	return s.setCronJobSuspend(ctx, namespace, name, true)
+}
+
+func (s *ClientImpl) ResumeCronJob(ctx context.Context, namespace string, name string) (*batchv1.CronJob, error) {
+	return s.setCronJobSuspend(ctx, namespace, name, false)
+}
+
+func (s *ClientImpl) setCronJobSuspend(ctx context.Context, namespace string, name string, suspend bool) (*batchv1.CronJob, error) {
+	opts := metav1.PatchOptions{FieldManager: FIELD_MANAGER}
+
+	data := fmt.Sprintf(`{"spec":{"suspend":%t}}`, suspend)
+	return s.clients.BatchV1().CronJobs(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(data), opts)
+}
+
+// TriggerCronJob runs the CronJob now by creating a Job from its jobTemplate,
+// owned by the CronJob and annotated like `kubectl create job --from`.
+func (s *ClientImpl) TriggerCronJob(ctx context.Context, namespace string, name string) (*batchv1.Job, error) {
+	cronJob, err := s.GetCronJob(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// keep the generated name within the 63 character label value limit
+	prefix := name
+	if len(prefix) > 50 {
+		prefix = prefix[:50]
+	}
+	jobName := fmt.Sprintf("%s-manual-%s", prefix, utilrand.String(5))
+
+	annotations := map[string]string{ANNOTATION_CRONJOB_INSTANTIATE: CRONJOB_INSTANTIATE_MANUAL}
+	for k, v := range cronJob.Spec.JobTemplate.Annotations {
+		annotations[k] = v
+	}
+	labels := make(map[string]string)
+	for k, v := range cronJob.Spec.JobTemplate.Labels {
+		labels[k] = v
+	}
+
+	controller := true
+	job := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{Kind: TYPEMETA_KIND_JOB, APIVersion: TYPEMETA_APIVERSION_BATCH_V1},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        jobName,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: TYPEMETA_APIVERSION_BATCH_V1,
+				Kind:       TYPEMETA_KIND_CRONJOB,
+				Name:       cronJob.Name,
+				UID:        cronJob.UID,
+				Controller: &controller,
+			}},
+		},
+		Spec: *cronJob.Spec.JobTemplate.Spec.DeepCopy(),
+	}
+
+	return s.CreateJob(ctx, namespace, job)
+}
+
+// CronJobHistory lists the Jobs owned by the CronJob, newest first.
+func (s *ClientImpl) CronJobHistory(ctx context.Context, namespace string, name string) ([]CronJobRun, error) {
+	cronJob, err := s.GetCronJob(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := s.ListJob(ctx, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []CronJobRun
+	for _, job := range jobs.Items {
+		owner := metav1.GetControllerOf(&job)
+		if owner == nil || owner.UID != cronJob.UID {
+			continue
+		}
+
+		run := CronJobRun{
+			Name:           job.Name,
+			Manual:         job.Annotations[ANNOTATION_CRONJOB_INSTANTIATE] == CRONJOB_INSTANTIATE_MANUAL,
+			Status:         JOB_STATUS_RUNNING,
+			Succeeded:      job.Status.Succeeded,
+			Failed:         job.Status.Failed,
+			CreationTime:   job.CreationTimestamp,
+			StartTime:      job.Status.StartTime,
+			CompletionTime: job.Status.CompletionTime,
+		}
+		for _, cond := range job.Status.Conditions {
+			if cond.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch cond.Type {
+			case batchv1.JobComplete:
+				run.Status = JOB_STATUS_SUCCEEDED
+			case batchv1.JobFailed:
+				run.Status = JOB_STATUS_FAILED
+			default:
+				continue
+			}
+			run.Reason = cond.Reason
+			run.Message = cond.Message
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[j].CreationTime.Before(&runs[i].CreationTime) })
+	return runs, nil
+}
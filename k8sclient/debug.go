@@ -0,0 +1,158 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	watch "k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+type DebugOptions struct {
+	Name            string // generated as debugger-xxxxx when empty
+	Image           string
+	TargetContainer string // container whose process namespace is shared
+	Command         []string
+	Timeout         time.Duration // how long to wait for the container to start, 0 waits on ctx only
+	Stdin           io.Reader
+	Stdout          io.Writer
+	Stderr          io.Writer
+	Tty             bool
+}
+
+func (s *ClientImpl) AddEphemeralContainer(ctx context.Context, namespace string, name string, opts DebugOptions) (*corev1.Pod, string, error) {
+	pod, err := s.GetPod(ctx, namespace, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	containerName := opts.Name
+	if containerName == "" {
+		containerName = fmt.Sprintf("debugger-%s", utilrand.String(5))
+	}
+
+	interactive := opts.Stdin != nil
+	ec := corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                     containerName,
+			Image:                    opts.Image,
+			Command:                  opts.Command,
+			ImagePullPolicy:          corev1.PullIfNotPresent,
+			TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+			Stdin:                    interactive,
+			StdinOnce:                interactive,
+			TTY:                      opts.Tty,
+		},
+		TargetContainerName: opts.TargetContainer,
+	}
+
+	updated := pod.DeepCopy()
+	updated.Spec.EphemeralContainers = append(updated.Spec.EphemeralContainers, ec)
+
+	opt := metav1.UpdateOptions{FieldManager: FIELD_MANAGER}
+	result, err := s.clients.CoreV1().Pods(namespace).UpdateEphemeralContainers(ctx, name, updated, opt)
+	if err != nil {
+		return nil, "", err
+	}
+	return result, containerName, nil
+}
+
+// WaitEphemeralContainer watches the pod from resourceVersion until the named
+// ephemeral container is running. It fails early when the container
+// terminates or its image cannot be pulled.
+func (s *ClientImpl) WaitEphemeralContainer(ctx context.Context, namespace string, name string, resourceVersion string, container string) (*corev1.Pod, error) {
+	opt := metav1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+		ResourceVersion: resourceVersion,
+	}
+	w, err := s.clients.CoreV1().Pods(namespace).Watch(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := watchtools.UntilWithoutRetry(ctx, w, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("pod %s/%s deleted", namespace, name)
+		}
+		pod, ok := event.Object.(*corev1.Pod)
+		if !ok {
+			return false, nil
+		}
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name != container {
+				continue
+			}
+			switch {
+			case status.State.Running != nil:
+				return true, nil
+			case status.State.Terminated != nil:
+				return false, fmt.Errorf("ephemeral container %s terminated: %s %s", container, status.State.Terminated.Reason, status.State.Terminated.Message)
+			case status.State.Waiting != nil:
+				switch status.State.Waiting.Reason {
+				case "ErrImagePull", "ImagePullBackOff", "InvalidImageName":
+					return false, fmt.Errorf("ephemeral container %s: %s %s", container, status.State.Waiting.Reason, status.State.Waiting.Message)
+				}
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event.Object.(*corev1.Pod), nil
+}
+
+func (s *ClientImpl) AttachPod(ctx context.Context, namespace string, name string, opts ExecOptions) error {
+	attachOpt := &corev1.PodAttachOptions{
+		Container: opts.Container,
+		Stdin:     opts.Stdin != nil,
+		Stdout:    opts.Stdout != nil,
+		Stderr:    opts.Stderr != nil && !opts.Tty,
+		TTY:       opts.Tty,
+	}
+
+	req := s.clients.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(name).SubResource("attach").
+		VersionedParams(attachOpt, scheme.ParameterCodec)
+
+	return s.stream(ctx, req.URL(), opts)
+}
+
+// DebugPod adds an ephemeral debug container to a running pod, waits for it to
+// start and, when any stream is set, attaches to it. It returns the name of
+// the debug container.
+func (s *ClientImpl) DebugPod(ctx context.Context, namespace string, name string, opts DebugOptions) (string, error) {
+	pod, container, err := s.AddEphemeralContainer(ctx, namespace, name, opts)
+	if err != nil {
+		return "", err
+	}
+
+	waitCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	if _, err := s.WaitEphemeralContainer(waitCtx, namespace, name, pod.ResourceVersion, container); err != nil {
+		return container, err
+	}
+
+	if opts.Stdin == nil && opts.Stdout == nil && opts.Stderr == nil {
+		return container, nil
+	}
+	attachOpt := ExecOptions{
+		Container: container,
+		Stdin:     opts.Stdin,
+		Stdout:    opts.Stdout,
+		Stderr:    opts.Stderr,
+		Tty:       opts.Tty,
+	}
+	return container, s.AttachPod(ctx, namespace, name, attachOpt)
+}
@@ -0,0 +1,230 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	eventv1 "k8s.io/api/events/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+const podDiagnosisEventLimit = 10
+
+type ContainerDiagnosis struct {
+	Name         string
+	Init         bool
+	Ready        bool
+	RestartCount int32
+	State        string // Waiting, Running or Terminated
+	Reason       string
+	Message      string
+	ExitCode     *int32
+
+	LastReason     string
+	LastMessage    string
+	LastExitCode   *int32
+	LastFinishedAt time.Time
+}
+
+type PodDiagnosis struct {
+	Namespace       string
+	Name            string
+	Node            string
+	Phase           corev1.PodPhase
+	ReadyContainers int
+	TotalContainers int
+	Ready           string // "x/y" as shown by kubectl get pods
+	Status          string // STATUS column of kubectl get pods, e.g. CrashLoopBackOff
+	Restarts        int32
+	Age             time.Duration
+	Containers      []ContainerDiagnosis
+	Warnings        []EventSummary
+}
+
+func (s *ClientImpl) DiagnosePod(ctx context.Context, namespace string, name string) (*PodDiagnosis, error) {
+	pod, err := s.GetPod(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.listWarningEvents(ctx, namespace, fields.Set{"regarding.uid": string(pod.UID)})
+	if err != nil {
+		return nil, err
+	}
+
+	d := NewPodDiagnosis(pod, events.Items)
+	return &d, nil
+}
+
+func (s *ClientImpl) DiagnosePods(ctx context.Context, namespace string, selector string) ([]PodDiagnosis, error) {
+	pods, err := s.ListPod(ctx, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.listWarningEvents(ctx, namespace, fields.Set{"regarding.kind": TYPEMETA_KIND_POD})
+	if err != nil {
+		return nil, err
+	}
+	byUID := make(map[string][]eventv1.Event)
+	for _, event := range events.Items {
+		uid := string(event.Regarding.UID)
+		byUID[uid] = append(byUID[uid], event)
+	}
+
+	r := make([]PodDiagnosis, 0, len(pods.Items))
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		r = append(r, NewPodDiagnosis(pod, byUID[string(pod.UID)]))
+	}
+	return r, nil
+}
+
+// NewPodDiagnosis summarizes a pod the way `kubectl get pods` does and attaches
+// the most recent of the given warning events.
+func NewPodDiagnosis(pod *corev1.Pod, events []eventv1.Event) PodDiagnosis {
+	d := PodDiagnosis{
+		Namespace:       pod.Namespace,
+		Name:            pod.Name,
+		Node:            pod.Spec.NodeName,
+		Phase:           pod.Status.Phase,
+		TotalContainers: len(pod.Spec.Containers),
+		Status:          podStatusReason(pod),
+	}
+	if !pod.CreationTimestamp.IsZero() {
+		d.Age = time.Since(pod.CreationTimestamp.Time)
+	}
+
+	for _, status := range pod.Status.InitContainerStatuses {
+		d.Containers = append(d.Containers, diagnoseContainer(status, true))
+		d.Restarts += status.RestartCount
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		d.Containers = append(d.Containers, diagnoseContainer(status, false))
+		d.Restarts += status.RestartCount
+		if status.Ready && status.State.Running != nil {
+			d.ReadyContainers++
+		}
+	}
+	d.Ready = fmt.Sprintf("%d/%d", d.ReadyContainers, d.TotalContainers)
+
+	var warningEvents []eventv1.Event
+	for _, event := range events {
+		if event.Type == corev1.EventTypeWarning {
+			warningEvents = append(warningEvents, event)
+		}
+	}
+	warnings := mergeEvents(warningEvents)
+	if len(warnings) > podDiagnosisEventLimit {
+		warnings = warnings[:podDiagnosisEventLimit]
+	}
+	d.Warnings = warnings
+
+	return d
+}
+
+func diagnoseContainer(status corev1.ContainerStatus, init bool) ContainerDiagnosis {
+	d := ContainerDiagnosis{
+		Name:         status.Name,
+		Init:         init,
+		Ready:        status.Ready,
+		RestartCount: status.RestartCount,
+	}
+
+	switch state := status.State; {
+	case state.Waiting != nil:
+		d.State = "Waiting"
+		d.Reason = state.Waiting.Reason
+		d.Message = state.Waiting.Message
+	case state.Running != nil:
+		d.State = "Running"
+	case state.Terminated != nil:
+		d.State = "Terminated"
+		d.Reason = state.Terminated.Reason
+		d.Message = state.Terminated.Message
+		exitCode := state.Terminated.ExitCode
+		d.ExitCode = &exitCode
+	}
+
+	if last := status.LastTerminationState.Terminated; last != nil {
+		d.LastReason = last.Reason
+		d.LastMessage = last.Message
+		exitCode := last.ExitCode
+		d.LastExitCode = &exitCode
+		d.LastFinishedAt = last.FinishedAt.Time
+	}
+	return d
+}
+
+// podStatusReason follows the STATUS column logic of kubectl's pod printer.
+func podStatusReason(pod *corev1.Pod) string {
+	reason := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		reason = pod.Status.Reason
+	}
+
+	initializing := false
+	for i, status := range pod.Status.InitContainerStatuses {
+		switch {
+		case status.State.Terminated != nil && status.State.Terminated.ExitCode == 0:
+			continue
+		case status.State.Terminated != nil:
+			if status.State.Terminated.Reason == "" {
+				if status.State.Terminated.Signal != 0 {
+					reason = fmt.Sprintf("Init:Signal:%d", status.State.Terminated.Signal)
+				} else {
+					reason = fmt.Sprintf("Init:ExitCode:%d", status.State.Terminated.ExitCode)
+				}
+			} else {
+				reason = "Init:" + status.State.Terminated.Reason
+			}
+		case status.State.Waiting != nil && status.State.Waiting.Reason != "" && status.State.Waiting.Reason != "PodInitializing":
+			reason = "Init:" + status.State.Waiting.Reason
+		default:
+			reason = fmt.Sprintf("Init:%d/%d", i, len(pod.Spec.InitContainers))
+		}
+		initializing = true
+		break
+	}
+
+	if !initializing {
+		hasRunning := false
+		for i := len(pod.Status.ContainerStatuses) - 1; i >= 0; i-- {
+			status := pod.Status.ContainerStatuses[i]
+			switch {
+			case status.State.Waiting != nil && status.State.Waiting.Reason != "":
+				reason = status.State.Waiting.Reason
+			case status.State.Terminated != nil && status.State.Terminated.Reason != "":
+				reason = status.State.Terminated.Reason
+			case status.State.Terminated != nil:
+				if status.State.Terminated.Signal != 0 {
+					reason = fmt.Sprintf("Signal:%d", status.State.Terminated.Signal)
+				} else {
+					reason = fmt.Sprintf("ExitCode:%d", status.State.Terminated.ExitCode)
+				}
+			case status.Ready && status.State.Running != nil:
+				hasRunning = true
+			}
+		}
+
+		if reason == "Completed" && hasRunning {
+			reason = "NotReady"
+			for _, cond := range pod.Status.Conditions {
+				if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+					reason = string(corev1.PodRunning)
+				}
+			}
+		}
+	}
+
+	if pod.DeletionTimestamp != nil {
+		if pod.Status.Reason == "NodeLost" {
+			reason = "Unknown"
+		} else {
+			reason = "Terminating"
+		}
+	}
+	return reason
+}
@@ -0,0 +1,223 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	watch "k8s.io/apimachinery/pkg/watch"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+type ServiceAddress struct {
+	IP       string
+	Hostname string
+	NodeName string
+	Zone     string
+	Pod      string // name of the target pod, when the endpoint refers to one
+	// Ports of the EndpointSlices listing this address. A named targetPort
+	// may resolve to different numbers on different pods.
+	Ports []ServicePort
+}
+
+type ServicePort struct {
+	Name        string
+	Port        int32
+	Protocol    corev1.Protocol
+	AppProtocol string
+}
+
+type ServiceEndpoints struct {
+	Namespace string
+	Name      string
+	Addresses []ServiceAddress // ready addresses only
+	// Ready counts distinct ready endpoints; a dual-stack pod listed in an
+	// IPv4 and an IPv6 slice counts once.
+	Ready int
+}
+
+func (s *ClientImpl) ListEndpointSlice(ctx context.Context, namespace string, selector string) (*discoveryv1.EndpointSliceList, error) {
+	opts := metav1.ListOptions{}
+	if selector != "" {
+		opts.LabelSelector = selector
+	}
+
+	return s.clients.DiscoveryV1().EndpointSlices(namespace).List(ctx, opts)
+}
+
+func (s *ClientImpl) GetEndpointSlice(ctx context.Context, namespace string, name string) (*discoveryv1.EndpointSlice, error) {
+	opt := metav1.GetOptions{}
+	return s.clients.DiscoveryV1().EndpointSlices(namespace).Get(ctx, name, opt)
+}
+
+func (s *ClientImpl) WatchEndpointSlices(ctx context.Context, namespace string, selector string) (watch.Interface, error) {
+	opt := metav1.ListOptions{}
+	if selector != "" {
+		opt.LabelSelector = selector
+	}
+
+	return s.clients.DiscoveryV1().EndpointSlices(namespace).Watch(ctx, opt)
+}
+
+// ResolveService merges all EndpointSlices of a Service into its ready
+// addresses, each with the ports of the slices that list it.
+func (s *ClientImpl) ResolveService(ctx context.Context, namespace string, name string) (*ServiceEndpoints, error) {
+	slices, err := s.ListEndpointSlice(ctx, namespace, serviceSliceSelector(name))
+	if err != nil {
+		return nil, err
+	}
+	return mergeEndpointSlices(namespace, name, slices.Items), nil
+}
+
+// WaitServiceEndpoints blocks until the Service has at least count ready
+// endpoints, see ServiceEndpoints.Ready.
+func (s *ClientImpl) WaitServiceEndpoints(ctx context.Context, namespace string, name string, count int) (*ServiceEndpoints, error) {
+	selector := serviceSliceSelector(name)
+	list, err := s.ListEndpointSlice(ctx, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	slices := make(map[string]discoveryv1.EndpointSlice)
+	for _, slice := range list.Items {
+		slices[slice.Name] = slice
+	}
+	merged := func() *ServiceEndpoints {
+		items := make([]discoveryv1.EndpointSlice, 0, len(slices))
+		for _, slice := range slices {
+			items = append(items, slice)
+		}
+		return mergeEndpointSlices(namespace, name, items)
+	}
+
+	result := merged()
+	if result.Ready >= count {
+		return result, nil
+	}
+
+	opt := metav1.ListOptions{LabelSelector: selector, ResourceVersion: list.ResourceVersion}
+	w, err := s.clients.DiscoveryV1().EndpointSlices(namespace).Watch(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = watchtools.UntilWithoutRetry(ctx, w, func(event watch.Event) (bool, error) {
+		slice, ok := event.Object.(*discoveryv1.EndpointSlice)
+		if !ok {
+			return false, nil
+		}
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			slices[slice.Name] = *slice
+		case watch.Deleted:
+			delete(slices, slice.Name)
+		}
+		result = merged()
+		return result.Ready >= count, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service %s/%s has %d of %d ready endpoints: %v", namespace, name, result.Ready, count, err)
+	}
+	return result, nil
+}
+
+func serviceSliceSelector(name string) string {
+	return labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: name}).String()
+}
+
+func mergeEndpointSlices(namespace string, name string, slices []discoveryv1.EndpointSlice) *ServiceEndpoints {
+	r := &ServiceEndpoints{Namespace: namespace, Name: name}
+	byIP := make(map[string]*ServiceAddress)
+	ready := make(map[string]bool)
+
+	for _, slice := range slices {
+		ports := make([]ServicePort, 0, len(slice.Ports))
+		for _, port := range slice.Ports {
+			p := ServicePort{}
+			if port.Name != nil {
+				p.Name = *port.Name
+			}
+			if port.Port != nil {
+				p.Port = *port.Port
+			}
+			if port.Protocol != nil {
+				p.Protocol = *port.Protocol
+			}
+			if port.AppProtocol != nil {
+				p.AppProtocol = *port.AppProtocol
+			}
+			ports = append(ports, p)
+		}
+
+		for _, endpoint := range slice.Endpoints {
+			// a nil ready condition means ready
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			if len(endpoint.Addresses) == 0 {
+				continue
+			}
+			ready[endpointKey(endpoint)] = true
+			for _, ip := range endpoint.Addresses {
+				addr, ok := byIP[ip]
+				if !ok {
+					addr = &ServiceAddress{IP: ip}
+					if endpoint.Hostname != nil {
+						addr.Hostname = *endpoint.Hostname
+					}
+					if endpoint.NodeName != nil {
+						addr.NodeName = *endpoint.NodeName
+					}
+					if endpoint.Zone != nil {
+						addr.Zone = *endpoint.Zone
+					}
+					if endpoint.TargetRef != nil && endpoint.TargetRef.Kind == TYPEMETA_KIND_POD {
+						addr.Pod = endpoint.TargetRef.Name
+					}
+					byIP[ip] = addr
+				}
+				addr.Ports = appendServicePorts(addr.Ports, ports)
+			}
+		}
+	}
+
+	for _, addr := range byIP {
+		sort.Slice(addr.Ports, func(i, j int) bool { return addr.Ports[i].Name < addr.Ports[j].Name })
+		r.Addresses = append(r.Addresses, *addr)
+	}
+	sort.Slice(r.Addresses, func(i, j int) bool { return r.Addresses[i].IP < r.Addresses[j].IP })
+	r.Ready = len(ready)
+	return r
+}
+
+// endpointKey identifies an endpoint across the slices of each address
+// family, by its target object when it has one.
+func endpointKey(endpoint discoveryv1.Endpoint) string {
+	if ref := endpoint.TargetRef; ref != nil {
+		if ref.UID != "" {
+			return string(ref.UID)
+		}
+		return ref.Kind + "/" + ref.Namespace + "/" + ref.Name
+	}
+	return endpoint.Addresses[0]
+}
+
+func appendServicePorts(ports []ServicePort, add []ServicePort) []ServicePort {
+	for _, p := range add {
+		found := false
+		for _, existing := range ports {
+			if existing == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
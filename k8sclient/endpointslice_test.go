@@ -0,0 +1,67 @@
+package k8sclient
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func testEndpointSlice(addressType discoveryv1.AddressType, port int32, endpoints ...discoveryv1.Endpoint) discoveryv1.EndpointSlice {
+	name := "http"
+	protocol := corev1.ProtocolTCP
+	return discoveryv1.EndpointSlice{
+		AddressType: addressType,
+		Endpoints:   endpoints,
+		Ports:       []discoveryv1.EndpointPort{{Name: &name, Port: &port, Protocol: &protocol}},
+	}
+}
+
+func testEndpoint(ip string, pod string, ready bool) discoveryv1.Endpoint {
+	return discoveryv1.Endpoint{
+		Addresses:  []string{ip},
+		Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+		TargetRef:  &corev1.ObjectReference{Kind: TYPEMETA_KIND_POD, Name: pod, UID: types.UID(pod + "-uid")},
+	}
+}
+
+func TestMergeEndpointSlicesDualStack(t *testing.T) {
+	slices := []discoveryv1.EndpointSlice{
+		testEndpointSlice(discoveryv1.AddressTypeIPv4, 8080,
+			testEndpoint("10.0.0.1", "a", true),
+			testEndpoint("10.0.0.2", "b", true),
+			testEndpoint("10.0.0.3", "c", false)),
+		testEndpointSlice(discoveryv1.AddressTypeIPv6, 8080,
+			testEndpoint("fd00::1", "a", true),
+			testEndpoint("fd00::2", "b", true),
+			testEndpoint("fd00::3", "c", false)),
+	}
+
+	r := mergeEndpointSlices("ns", "svc", slices)
+	if r.Ready != 2 {
+		t.Errorf("Ready = %d, want 2", r.Ready)
+	}
+	if len(r.Addresses) != 4 {
+		t.Errorf("got %d addresses, want 4", len(r.Addresses))
+	}
+}
+
+func TestMergeEndpointSlicesPortsPerSlice(t *testing.T) {
+	// a named targetPort resolving to different numbers on different pods
+	slices := []discoveryv1.EndpointSlice{
+		testEndpointSlice(discoveryv1.AddressTypeIPv4, 8080, testEndpoint("10.0.0.1", "a", true)),
+		testEndpointSlice(discoveryv1.AddressTypeIPv4, 9090, testEndpoint("10.0.0.2", "b", true)),
+	}
+
+	r := mergeEndpointSlices("ns", "svc", slices)
+	want := map[string]int32{"10.0.0.1": 8080, "10.0.0.2": 9090}
+	if len(r.Addresses) != len(want) {
+		t.Fatalf("got %d addresses, want %d", len(r.Addresses), len(want))
+	}
+	for _, addr := range r.Addresses {
+		if len(addr.Ports) != 1 || addr.Ports[0].Port != want[addr.IP] {
+			t.Errorf("%s has ports %v, want only %d", addr.IP, addr.Ports, want[addr.IP])
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package k8sclient
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	eventv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	watch "k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+type EventSummary struct {
+	Type      string
+	Reason    string
+	Note      string
+	Count     int32
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Regarding corev1.ObjectReference
+	Reporter  string
+}
+
+type EventStreamOptions struct {
+	Kind    string   // only events regarding this kind, e.g. Pod
+	Name    string   // only events regarding this object name
+	Reasons []string // only these reasons, empty allows all
+	// IncludeExisting also sends the warnings already recorded when the stream
+	// starts, otherwise only new ones are sent.
+	IncludeExisting bool
+}
+
+// ListEventsFor lists the events regarding one object using regarding.* field
+// selectors, merged by reason and note and sorted newest first. An empty kind
+// matches any kind.
+func (s *ClientImpl) ListEventsFor(ctx context.Context, namespace string, kind string, name string) ([]EventSummary, error) {
+	set := fields.Set{"regarding.name": name}
+	if kind != "" {
+		set["regarding.kind"] = kind
+	}
+	opts := metav1.ListOptions{FieldSelector: set.AsSelector().String()}
+	events, err := s.clients.EventsV1().Events(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return mergeEvents(events.Items), nil
+}
+
+// StreamWarningEvents sends Warning events of the namespace as they are
+// recorded until ctx is done, then closes the channel. An empty namespace
+// streams the whole cluster.
+func (s *ClientImpl) StreamWarningEvents(ctx context.Context, namespace string, opts EventStreamOptions) (<-chan EventSummary, error) {
+	set := fields.Set{"type": corev1.EventTypeWarning}
+	if opts.Kind != "" {
+		set["regarding.kind"] = opts.Kind
+	}
+	if opts.Name != "" {
+		set["regarding.name"] = opts.Name
+	}
+	selector := set.AsSelector().String()
+
+	list, err := s.clients.EventsV1().Events(namespace).List(ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	lw := &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return s.clients.EventsV1().Events(namespace).Watch(ctx, options)
+		},
+	}
+	w, err := watchtools.NewRetryWatcher(list.ResourceVersion, lw)
+	if err != nil {
+		return nil, err
+	}
+
+	reasons := make(map[string]bool)
+	for _, reason := range opts.Reasons {
+		reasons[reason] = true
+	}
+	accept := func(event *eventv1.Event) bool {
+		return len(reasons) == 0 || reasons[event.Reason]
+	}
+
+	ch := make(chan EventSummary)
+	go func() {
+		defer close(ch)
+		defer w.Stop()
+
+		send := func(summary EventSummary) bool {
+			select {
+			case ch <- summary:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if opts.IncludeExisting {
+			for _, summary := range mergeEvents(list.Items) {
+				if (len(reasons) == 0 || reasons[summary.Reason]) && !send(summary) {
+					return
+				}
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case result, ok := <-w.ResultChan():
+				if !ok {
+					return
+				}
+				if result.Type != watch.Added && result.Type != watch.Modified {
+					continue
+				}
+				event, ok := result.Object.(*eventv1.Event)
+				if !ok || !accept(event) {
+					continue
+				}
+				if !send(summarizeEvent(event)) {
+					return
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+func (s *ClientImpl) listWarningEvents(ctx context.Context, namespace string, selector fields.Set) (*eventv1.EventList, error) {
+	set := fields.Set{"type": corev1.EventTypeWarning}
+	for k, v := range selector {
+		set[k] = v
+	}
+	opts := metav1.ListOptions{FieldSelector: set.AsSelector().String()}
+	return s.clients.EventsV1().Events(namespace).List(ctx, opts)
+}
+
+// mergeEvents folds events that repeat the same reason and note for the same
+// object into one summary, adding up their series counts.
+func mergeEvents(events []eventv1.Event) []EventSummary {
+	type key struct {
+		uid, kind, name, eventType, reason, note string
+	}
+	merged := make(map[key]*EventSummary)
+	var order []key
+
+	for i := range events {
+		event := &events[i]
+		k := key{string(event.Regarding.UID), event.Regarding.Kind, event.Regarding.Name, event.Type, event.Reason, event.Note}
+		summary := summarizeEvent(event)
+		existing, ok := merged[k]
+		if !ok {
+			merged[k] = &summary
+			order = append(order, k)
+			continue
+		}
+		existing.Count += summary.Count
+		if summary.FirstSeen.Before(existing.FirstSeen) {
+			existing.FirstSeen = summary.FirstSeen
+		}
+		if summary.LastSeen.After(existing.LastSeen) {
+			existing.LastSeen = summary.LastSeen
+		}
+	}
+
+	r := make([]EventSummary, 0, len(order))
+	for _, k := range order {
+		r = append(r, *merged[k])
+	}
+	sort.SliceStable(r, func(i, j int) bool { return r[i].LastSeen.After(r[j].LastSeen) })
+	return r
+}
+
+func eventFirstSeen(event *eventv1.Event) time.Time {
+	switch {
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.DeprecatedFirstTimestamp.IsZero():
+		return event.DeprecatedFirstTimestamp.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+func eventLastSeen(event *eventv1.Event) time.Time {
+	switch {
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	case !event.DeprecatedLastTimestamp.IsZero():
+		return event.DeprecatedLastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+func eventCount(event *eventv1.Event) int32 {
+	switch {
+	case event.Series != nil:
+		return event.Series.Count
+	case event.DeprecatedCount > 0:
+		return event.DeprecatedCount
+	}
+	return 1
+}
+
+func summarizeEvent(event *eventv1.Event) EventSummary {
+	return EventSummary{
+		Type:      event.Type,
+		Reason:    event.Reason,
+		Note:      event.Note,
+		Count:     eventCount(event),
+		FirstSeen: eventFirstSeen(event),
+		LastSeen:  eventLastSeen(event),
+		Regarding: event.Regarding,
+		Reporter:  event.ReportingController,
+	}
+}
@@ -0,0 +1,111 @@
+package k8sclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const evictDefaultRetryInterval = 5 * time.Second
+
+// DisruptionBudgetError is returned when an eviction is refused because it
+// would violate a PodDisruptionBudget.
+type DisruptionBudgetError struct {
+	Namespace string
+	Name      string
+	Err       error
+}
+
+func (e *DisruptionBudgetError) Error() string {
+	return fmt.Sprintf("eviction of pod %s/%s blocked by PodDisruptionBudget: %v", e.Namespace, e.Name, e.Err)
+}
+
+func (e *DisruptionBudgetError) Unwrap() error {
+	return e.Err
+}
+
+func IsDisruptionBudgetError(err error) bool {
+	var target *DisruptionBudgetError
+	return errors.As(err, &target)
+}
+
+type EvictOptions struct {
+	GracePeriodSeconds *int64        // overrides the pod's own grace period when set
+	Retry              bool          // keep retrying while blocked by a PodDisruptionBudget
+	RetryInterval      time.Duration // defaults to 5s
+	// Timeout bounds the retry loop of each pod, 0 retries until ctx is done.
+	// API server throttling is always retried within it.
+	Timeout time.Duration
+	// OnBlocked is called before each retry with the DisruptionBudgetError.
+	OnBlocked func(error)
+}
+
+func (s *ClientImpl) EvictPod(ctx context.Context, namespace string, name string, opts EvictOptions) error {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = evictDefaultRetryInterval
+	}
+
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+	}
+	if opts.GracePeriodSeconds != nil {
+		eviction.DeleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: opts.GracePeriodSeconds}
+	}
+
+	for {
+		err := s.clients.CoreV1().Pods(namespace).EvictV1(ctx, eviction)
+		if err == nil || apierrors.IsNotFound(err) {
+			return nil
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			return err
+		}
+
+		wait := opts.RetryInterval
+		if apierrors.HasStatusCause(err, policyv1.DisruptionBudgetCause) {
+			err = &DisruptionBudgetError{Namespace: namespace, Name: name, Err: err}
+			if !opts.Retry {
+				return err
+			}
+			if opts.OnBlocked != nil {
+				opts.OnBlocked(err)
+			}
+		} else if seconds, ok := apierrors.SuggestsClientDelay(err); ok && seconds > 0 {
+			// throttled by API priority and fairness, not by a budget
+			wait = time.Duration(seconds) * time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// EvictPods evicts every pod matching the selector. Pods blocked by a
+// PodDisruptionBudget are reported in the returned aggregate error.
+func (s *ClientImpl) EvictPods(ctx context.Context, namespace string, selector string, opts EvictOptions) error {
+	pods, err := s.ListPod(ctx, namespace, selector)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, pod := range pods.Items {
+		if err := s.EvictPod(ctx, pod.Namespace, pod.Name, opts); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
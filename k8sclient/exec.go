@@ -0,0 +1,68 @@
+package k8sclient
+
+import (
+	"context"
+	"io"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+type ExecOptions struct {
+	Container string
+	Command   []string
+	Stdin     io.Reader
+	Stdout    io.Writer
+	Stderr    io.Writer
+	Tty       bool
+}
+
+func (s *ClientImpl) ExecPod(ctx context.Context, namespace string, name string, opts ExecOptions) error {
+	execOpt := &corev1.PodExecOptions{
+		Container: opts.Container,
+		Command:   opts.Command,
+		Stdin:     opts.Stdin != nil,
+		Stdout:    opts.Stdout != nil,
+		Stderr:    opts.Stderr != nil && !opts.Tty,
+		TTY:       opts.Tty,
+	}
+
+	req := s.clients.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(name).SubResource("exec").
+		VersionedParams(execOpt, scheme.ParameterCodec)
+
+	return s.stream(ctx, req.URL(), opts)
+}
+
+// stream runs a SPDY exec/attach session until it finishes or ctx is done.
+// remotecommand in this client-go version has no context support, so the
+// session is left to unwind on its own once ctx is cancelled.
+func (s *ClientImpl) stream(ctx context.Context, u *url.URL, opts ExecOptions) error {
+	executor, err := remotecommand.NewSPDYExecutor(s.config, "POST", u)
+	if err != nil {
+		return err
+	}
+
+	streamOpt := remotecommand.StreamOptions{
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Tty:    opts.Tty,
+	}
+	if !opts.Tty {
+		streamOpt.Stderr = opts.Stderr
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- executor.Stream(streamOpt)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,134 @@
+package k8sclient
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+type GPUAllocation struct {
+	Namespace string          `json:"namespace"`
+	Pod       string          `json:"pod"`
+	Node      string          `json:"node"`
+	User      string          `json:"user"`
+	Phase     corev1.PodPhase `json:"phase"`
+	GPUs      int64           `json:"gpus"`
+}
+
+type NodeGPU struct {
+	Name        string            `json:"name"`
+	Product     string            `json:"product"`
+	Labels      map[string]string `json:"labels"` // nvidia.com/* node labels
+	Capacity    int64             `json:"capacity"`
+	Allocatable int64             `json:"allocatable"`
+	Allocated   int64             `json:"allocated"`
+	Free        int64             `json:"free"`
+	Pods        []GPUAllocation   `json:"pods"`
+}
+
+type GPUInventory struct {
+	Nodes       []NodeGPU        `json:"nodes"`
+	ByUser      map[string]int64 `json:"byUser"` // pods without a username label count under ""
+	ByNamespace map[string]int64 `json:"byNamespace"`
+	Capacity    int64            `json:"capacity"`
+	Allocatable int64            `json:"allocatable"`
+	Allocated   int64            `json:"allocated"`
+	Free        int64            `json:"free"`
+}
+
+// GPUInventory lists the GPUs of every node that advertises nvidia.com/gpu and
+// the scheduled pods holding them, with totals per user and per namespace.
+func (s *ClientImpl) GPUInventory(ctx context.Context) (*GPUInventory, error) {
+	nodes, err := s.ListNode(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	pods, err := s.listNonTerminatedPods(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	byNode := make(map[string][]GPUAllocation)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		gpus := podGPUs(pod)
+		if gpus == 0 {
+			continue
+		}
+		byNode[pod.Spec.NodeName] = append(byNode[pod.Spec.NodeName], GPUAllocation{
+			Namespace: pod.Namespace,
+			Pod:       pod.Name,
+			Node:      pod.Spec.NodeName,
+			User:      PodUsername(pod),
+			Phase:     pod.Status.Phase,
+			GPUs:      gpus,
+		})
+	}
+
+	inventory := &GPUInventory{ByUser: make(map[string]int64), ByNamespace: make(map[string]int64)}
+	gpu := corev1.ResourceName(LABEL_NVIDIA_GPU)
+	for _, node := range nodes.Items {
+		capacity := node.Status.Capacity[gpu]
+		allocatable := node.Status.Allocatable[gpu]
+		if capacity.IsZero() && len(byNode[node.Name]) == 0 {
+			continue
+		}
+
+		n := NodeGPU{
+			Name:        node.Name,
+			Product:     node.Labels[LABEL_NVIDIA_GPU_PRODUCT],
+			Labels:      make(map[string]string),
+			Capacity:    capacity.Value(),
+			Allocatable: allocatable.Value(),
+			Pods:        byNode[node.Name],
+		}
+		for k, v := range node.Labels {
+			if strings.HasPrefix(k, "nvidia.com/") {
+				n.Labels[k] = v
+			}
+		}
+		for _, alloc := range n.Pods {
+			n.Allocated += alloc.GPUs
+			inventory.ByUser[alloc.User] += alloc.GPUs
+			inventory.ByNamespace[alloc.Namespace] += alloc.GPUs
+		}
+		if n.Free = n.Allocatable - n.Allocated; n.Free < 0 {
+			n.Free = 0
+		}
+
+		inventory.Capacity += n.Capacity
+		inventory.Allocatable += n.Allocatable
+		inventory.Allocated += n.Allocated
+		inventory.Free += n.Free
+		inventory.Nodes = append(inventory.Nodes, n)
+	}
+	sort.Slice(inventory.Nodes, func(i, j int) bool { return inventory.Nodes[i].Name < inventory.Nodes[j].Name })
+
+	return inventory, nil
+}
+
+// PodUsername returns the idpp2 owner of a pod, falling back to the
+// JupyterHub username label.
+func PodUsername(pod *corev1.Pod) string {
+	if user := pod.Labels[LABEL_IDPP2_USERNAME]; user != "" {
+		return user
+	}
+	return pod.Labels[LABEL_JUPYTERUSERNAME]
+}
+
+func podGPUs(pod *corev1.Pod) int64 {
+	gpu := corev1.ResourceName(LABEL_NVIDIA_GPU)
+	reqs, limits := podRequestsAndLimits(pod)
+	if quantity, ok := reqs[gpu]; ok {
+		return quantity.Value()
+	}
+	if quantity, ok := limits[gpu]; ok {
+		return quantity.Value()
+	}
+	return 0
+}
@@ -0,0 +1,252 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	configautoscalingv2 "k8s.io/client-go/applyconfigurations/autoscaling/v2"
+	configmetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+type HPAMetricStatus struct {
+	Type    autoscalingv2.MetricSourceType
+	Name    string // resource or metric name
+	Target  string
+	Current string // empty until the metric has been read
+}
+
+type HPAStatusSummary struct {
+	Namespace       string
+	Name            string
+	MinReplicas     int32
+	MaxReplicas     int32
+	CurrentReplicas int32
+	DesiredReplicas int32
+	LastScaleTime   *metav1.Time
+	Metrics         []HPAMetricStatus
+
+	// Blocked is set when a condition explains why the autoscaler cannot
+	// reach the replica count its metrics ask for.
+	Blocked   bool
+	Condition autoscalingv2.HorizontalPodAutoscalerConditionType
+	Reason    string
+	Message   string
+}
+
+func (s *ClientImpl) ListHorizontalPodAutoscaler(ctx context.Context, namespace string, selector string) (*autoscalingv2.HorizontalPodAutoscalerList, error) {
+	opts := metav1.ListOptions{}
+	if selector != "" {
+		opts.LabelSelector = selector
+	}
+	return s.clients.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, opts)
+}
+
+func (s *ClientImpl) GetHorizontalPodAutoscaler(ctx context.Context, namespace string, name string) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	opt := metav1.GetOptions{}
+	return s.clients.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(ctx, name, opt)
+}
+
+func (s *ClientImpl) ApplyHorizontalPodAutoscaler(ctx context.Context, namespace string, config *configautoscalingv2.HorizontalPodAutoscalerApplyConfiguration) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	opt := metav1.ApplyOptions{FieldManager: FIELD_MANAGER}
+	return s.clients.AutoscalingV2().HorizontalPodAutoscalers(namespace).Apply(ctx, config, opt)
+}
+
+func (s *ClientImpl) DeleteHorizontalPodAutoscaler(ctx context.Context, namespace string, name string) error {
+	opt := metav1.DeleteOptions{} // == metav1.NewDeleteOptions(0)
+	return s.clients.AutoscalingV2().HorizontalPodAutoscalers(namespace).Delete(ctx, name, opt)
+}
+
+func (s *ClientImpl) DeleteHorizontalPodAutoscalers(ctx context.Context, namespace string, selector string) error {
+	deleteOpt := metav1.DeleteOptions{}
+	selectOpt := metav1.ListOptions{}
+	if selector != "" {
+		selectOpt.LabelSelector = selector
+	}
+	return s.clients.AutoscalingV2().HorizontalPodAutoscalers(namespace).DeleteCollection(ctx, deleteOpt, selectOpt)
+}
+
+// AutoscaleDeployment applies an HPA with the Deployment's name that scales it
+// between minReplicas and maxReplicas on the given metrics.
+func (s *ClientImpl) AutoscaleDeployment(ctx context.Context, namespace string, name string, minReplicas int32, maxReplicas int32, metrics ...*configautoscalingv2.MetricSpecApplyConfiguration) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("autoscaler %s needs at least one metric", name)
+	}
+
+	spec := configautoscalingv2.HorizontalPodAutoscalerSpec().
+		WithScaleTargetRef(configautoscalingv2.CrossVersionObjectReference().
+			WithAPIVersion(TYPEMETA_APIVERSION_APPS_V1).
+			WithKind(TYPEMETA_KIND_DEPLOYMENT).
+			WithName(name)).
+		WithMinReplicas(minReplicas).
+		WithMaxReplicas(maxReplicas).
+		WithMetrics(metrics...)
+
+	config := configautoscalingv2.HorizontalPodAutoscaler(name, namespace).WithSpec(spec)
+	return s.ApplyHorizontalPodAutoscaler(ctx, namespace, config)
+}
+
+// HPAResourceUtilization targets an average utilization percentage of the
+// pods' requests for a resource.
+func HPAResourceUtilization(name corev1.ResourceName, averageUtilization int32) *configautoscalingv2.MetricSpecApplyConfiguration {
+	return configautoscalingv2.MetricSpec().
+		WithType(autoscalingv2.ResourceMetricSourceType).
+		WithResource(configautoscalingv2.ResourceMetricSource().
+			WithName(name).
+			WithTarget(configautoscalingv2.MetricTarget().
+				WithType(autoscalingv2.UtilizationMetricType).
+				WithAverageUtilization(averageUtilization)))
+}
+
+func HPACPUUtilization(averageUtilization int32) *configautoscalingv2.MetricSpecApplyConfiguration {
+	return HPAResourceUtilization(corev1.ResourceCPU, averageUtilization)
+}
+
+func HPAMemoryUtilization(averageUtilization int32) *configautoscalingv2.MetricSpecApplyConfiguration {
+	return HPAResourceUtilization(corev1.ResourceMemory, averageUtilization)
+}
+
+// HPAPodsMetric targets an average value of a custom per-pod metric, such as
+// requests per second served by TF Serving.
+func HPAPodsMetric(name string, averageValue resource.Quantity) *configautoscalingv2.MetricSpecApplyConfiguration {
+	return configautoscalingv2.MetricSpec().
+		WithType(autoscalingv2.PodsMetricSourceType).
+		WithPods(configautoscalingv2.PodsMetricSource().
+			WithMetric(configautoscalingv2.MetricIdentifier().WithName(name)).
+			WithTarget(configautoscalingv2.MetricTarget().
+				WithType(autoscalingv2.AverageValueMetricType).
+				WithAverageValue(averageValue)))
+}
+
+// HPAExternalMetric targets an average value of a metric from outside the
+// cluster, such as a queue length. selector may be nil.
+func HPAExternalMetric(name string, selector map[string]string, averageValue resource.Quantity) *configautoscalingv2.MetricSpecApplyConfiguration {
+	metric := configautoscalingv2.MetricIdentifier().WithName(name)
+	if len(selector) > 0 {
+		metric.WithSelector(configmetav1.LabelSelector().WithMatchLabels(selector))
+	}
+	return configautoscalingv2.MetricSpec().
+		WithType(autoscalingv2.ExternalMetricSourceType).
+		WithExternal(configautoscalingv2.ExternalMetricSource().
+			WithMetric(metric).
+			WithTarget(configautoscalingv2.MetricTarget().
+				WithType(autoscalingv2.AverageValueMetricType).
+				WithAverageValue(averageValue)))
+}
+
+func (s *ClientImpl) HorizontalPodAutoscalerStatus(ctx context.Context, namespace string, name string) (*HPAStatusSummary, error) {
+	hpa, err := s.GetHorizontalPodAutoscaler(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	summary := SummarizeHorizontalPodAutoscaler(hpa)
+	return &summary, nil
+}
+
+func SummarizeHorizontalPodAutoscaler(hpa *autoscalingv2.HorizontalPodAutoscaler) HPAStatusSummary {
+	summary := HPAStatusSummary{
+		Namespace:       hpa.Namespace,
+		Name:            hpa.Name,
+		MaxReplicas:     hpa.Spec.MaxReplicas,
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		DesiredReplicas: hpa.Status.DesiredReplicas,
+		LastScaleTime:   hpa.Status.LastScaleTime,
+	}
+	if hpa.Spec.MinReplicas != nil {
+		summary.MinReplicas = *hpa.Spec.MinReplicas
+	} else {
+		summary.MinReplicas = 1
+	}
+
+	// current metrics are reported in the order of the spec
+	for i, spec := range hpa.Spec.Metrics {
+		m := hpaMetricSpec(spec)
+		if i < len(hpa.Status.CurrentMetrics) {
+			m.Current = hpaMetricCurrent(hpa.Status.CurrentMetrics[i])
+		}
+		summary.Metrics = append(summary.Metrics, m)
+	}
+
+	for _, cond := range hpa.Status.Conditions {
+		blocked := false
+		switch cond.Type {
+		case autoscalingv2.AbleToScale, autoscalingv2.ScalingActive:
+			blocked = cond.Status == corev1.ConditionFalse
+		case autoscalingv2.ScalingLimited:
+			blocked = cond.Status == corev1.ConditionTrue
+		}
+		if blocked {
+			summary.Blocked = true
+			summary.Condition = cond.Type
+			summary.Reason = cond.Reason
+			summary.Message = cond.Message
+			break
+		}
+	}
+	return summary
+}
+
+func hpaMetricSpec(spec autoscalingv2.MetricSpec) HPAMetricStatus {
+	m := HPAMetricStatus{Type: spec.Type}
+	switch {
+	case spec.Resource != nil:
+		m.Name = string(spec.Resource.Name)
+		m.Target = hpaMetricTarget(spec.Resource.Target)
+	case spec.ContainerResource != nil:
+		m.Name = fmt.Sprintf("%s/%s", spec.ContainerResource.Container, spec.ContainerResource.Name)
+		m.Target = hpaMetricTarget(spec.ContainerResource.Target)
+	case spec.Pods != nil:
+		m.Name = spec.Pods.Metric.Name
+		m.Target = hpaMetricTarget(spec.Pods.Target)
+	case spec.Object != nil:
+		m.Name = fmt.Sprintf("%s/%s %s", spec.Object.DescribedObject.Kind, spec.Object.DescribedObject.Name, spec.Object.Metric.Name)
+		m.Target = hpaMetricTarget(spec.Object.Target)
+	case spec.External != nil:
+		m.Name = spec.External.Metric.Name
+		m.Target = hpaMetricTarget(spec.External.Target)
+	}
+	return m
+}
+
+func hpaMetricCurrent(status autoscalingv2.MetricStatus) string {
+	switch {
+	case status.Resource != nil:
+		return hpaMetricValue(status.Resource.Current)
+	case status.ContainerResource != nil:
+		return hpaMetricValue(status.ContainerResource.Current)
+	case status.Pods != nil:
+		return hpaMetricValue(status.Pods.Current)
+	case status.Object != nil:
+		return hpaMetricValue(status.Object.Current)
+	case status.External != nil:
+		return hpaMetricValue(status.External.Current)
+	}
+	return ""
+}
+
+func hpaMetricTarget(target autoscalingv2.MetricTarget) string {
+	switch {
+	case target.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *target.AverageUtilization)
+	case target.AverageValue != nil:
+		return target.AverageValue.String()
+	case target.Value != nil:
+		return target.Value.String()
+	}
+	return ""
+}
+
+func hpaMetricValue(value autoscalingv2.MetricValueStatus) string {
+	switch {
+	case value.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *value.AverageUtilization)
+	case value.AverageValue != nil:
+		return value.AverageValue.String()
+	case value.Value != nil:
+		return value.Value.String()
+	}
+	return ""
+}
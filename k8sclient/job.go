@@ -0,0 +1,236 @@
+package k8sclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+const (
+	JOB_CLEANUP_NEVER      = "Never"
+	JOB_CLEANUP_ALWAYS     = "Always"
+	JOB_CLEANUP_ON_SUCCESS = "OnSuccess"
+)
+
+type JobRunOptions struct {
+	Timeout  time.Duration // bounds waiting for the Job to finish, 0 waits on ctx only
+	Cleanup  string        // one of the JOB_CLEANUP_* values, defaults to JOB_CLEANUP_NEVER
+	LogLines *int64        // tail lines of logs captured per container, nil captures everything
+}
+
+type JobContainerResult struct {
+	Name     string
+	ExitCode int32
+	Reason   string
+	Message  string // termination message
+	Logs     string
+}
+
+type JobPodResult struct {
+	Name       string
+	Phase      corev1.PodPhase
+	Containers []JobContainerResult
+}
+
+type JobResult struct {
+	Job            *batchv1.Job
+	Succeeded      bool
+	Reason         string
+	Message        string
+	StartTime      *metav1.Time
+	CompletionTime *metav1.Time
+	Pods           []JobPodResult
+}
+
+func (s *ClientImpl) CreateJob(ctx context.Context, namespace string, job *batchv1.Job) (*batchv1.Job, error) {
+	opt := metav1.CreateOptions{FieldManager: FIELD_MANAGER}
+	return s.clients.BatchV1().Jobs(namespace).Create(ctx, job, opt)
+}
+
+func (s *ClientImpl) CreateJobFromSpecs(ctx context.Context, namespace string, spec ResourceSpecs) (*batchv1.Job, error) {
+	job, err := ResourceSpecsToJob(spec)
+	if err != nil {
+		return nil, err
+	}
+	return s.CreateJob(ctx, namespace, job)
+}
+
+func ResourceSpecsToJob(spec ResourceSpecs) (*batchv1.Job, error) {
+	if kind := spec.GetKind(); kind != TYPEMETA_KIND_JOB {
+		return nil, fmt.Errorf("resource kind %s is not %s", kind, TYPEMETA_KIND_JOB)
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	job := &batchv1.Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// WaitJob watches the Job until it has a Complete or Failed condition.
+func (s *ClientImpl) WaitJob(ctx context.Context, namespace string, name string) (*batchv1.Job, error) {
+	job, err := s.GetJob(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if jobFinished(job) {
+		return job, nil
+	}
+
+	// UntilWithSync relists and rewatches when the server closes the watch,
+	// which it does every 30 to 60 minutes
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+			return s.clients.BatchV1().Jobs(namespace).List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return s.clients.BatchV1().Jobs(namespace).Watch(ctx, options)
+		},
+	}
+
+	event, err := watchtools.UntilWithSync(ctx, lw, &batchv1.Job{}, nil, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("job %s/%s deleted", namespace, name)
+		}
+		job, ok := event.Object.(*batchv1.Job)
+		return ok && jobFinished(job), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.ObjectToJob(event.Object), nil
+}
+
+// JobResult collects the outcome of a finished Job, including exit codes,
+// termination messages and logs of its pods.
+func (s *ClientImpl) JobResult(ctx context.Context, job *batchv1.Job, logLines *int64) (*JobResult, error) {
+	result := &JobResult{
+		Job:            job,
+		StartTime:      job.Status.StartTime,
+		CompletionTime: job.Status.CompletionTime,
+	}
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		if cond.Type == batchv1.JobComplete || cond.Type == batchv1.JobFailed {
+			result.Succeeded = cond.Type == batchv1.JobComplete
+			result.Reason = cond.Reason
+			result.Message = cond.Message
+		}
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := s.ListPod(ctx, job.Namespace, selector.String())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range pods.Items {
+		podResult := JobPodResult{Name: pod.Name, Phase: pod.Status.Phase}
+		for _, status := range pod.Status.ContainerStatuses {
+			c := JobContainerResult{Name: status.Name}
+			if terminated := status.State.Terminated; terminated != nil {
+				c.ExitCode = terminated.ExitCode
+				c.Reason = terminated.Reason
+				c.Message = terminated.Message
+			}
+			// logs are best effort, a container that never started has none
+			if logs, err := s.podLogs(ctx, pod.Namespace, pod.Name, status.Name, logLines); err == nil {
+				c.Logs = logs
+			}
+			podResult.Containers = append(podResult.Containers, c)
+		}
+		result.Pods = append(result.Pods, podResult)
+	}
+	return result, nil
+}
+
+// RunJob creates the Job, waits for it to finish, collects its result and
+// applies the cleanup policy. A failed Job is reported in the result, not as
+// an error.
+func (s *ClientImpl) RunJob(ctx context.Context, namespace string, job *batchv1.Job, opts JobRunOptions) (*JobResult, error) {
+	created, err := s.CreateJob(ctx, namespace, job)
+	if err != nil {
+		return nil, err
+	}
+
+	waitCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	finished, err := s.WaitJob(waitCtx, namespace, created.Name)
+	if err != nil {
+		// only a Job that ran out of time is removed, never one still running
+		// after some other failure
+		if opts.Cleanup == JOB_CLEANUP_ALWAYS && waitCtx.Err() != nil {
+			s.deleteJobWithPods(ctx, namespace, created.Name)
+		}
+		return nil, err
+	}
+
+	result, err := s.JobResult(ctx, finished, opts.LogLines)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Cleanup == JOB_CLEANUP_ALWAYS || (opts.Cleanup == JOB_CLEANUP_ON_SUCCESS && result.Succeeded) {
+		if err := s.deleteJobWithPods(ctx, namespace, created.Name); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (s *ClientImpl) RunJobFromSpecs(ctx context.Context, namespace string, spec ResourceSpecs, opts JobRunOptions) (*JobResult, error) {
+	job, err := ResourceSpecsToJob(spec)
+	if err != nil {
+		return nil, err
+	}
+	return s.RunJob(ctx, namespace, job, opts)
+}
+
+func (s *ClientImpl) deleteJobWithPods(ctx context.Context, namespace string, name string) error {
+	propagation := metav1.DeletePropagationBackground
+	opt := metav1.DeleteOptions{PropagationPolicy: &propagation}
+	return s.clients.BatchV1().Jobs(namespace).Delete(ctx, name, opt)
+}
+
+func (s *ClientImpl) podLogs(ctx context.Context, namespace string, name string, container string, tailLines *int64) (string, error) {
+	opt := &corev1.PodLogOptions{Container: container, TailLines: tailLines}
+	data, err := s.clients.CoreV1().Pods(namespace).GetLogs(name, opt).DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func jobFinished(job *batchv1.Job) bool {
+	for _, cond := range job.Status.Conditions {
+		if (cond.Type == batchv1.JobComplete || cond.Type == batchv1.JobFailed) && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	ENV_POD_NAME      = "POD_NAME"
+	ENV_POD_NAMESPACE = "POD_NAMESPACE"
+
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+	defaultLeaseDuration = 15 * time.Second
+	defaultRenewDeadline = 10 * time.Second
+	defaultRetryPeriod   = 2 * time.Second
+)
+
+type LeaderElectionOptions struct {
+	Name      string // name of the Lease
+	Namespace string // defaults to the pod namespace, see PodNamespace
+	Identity  string // defaults to the pod name, see PodName
+
+	LeaseDuration time.Duration // how long non-leaders wait before taking over, defaults to 15s
+	RenewDeadline time.Duration // how long the leader retries renewing before giving up, defaults to 10s
+	RetryPeriod   time.Duration // wait between acquire and renew attempts, defaults to 2s
+	// ReleaseOnCancel clears the Lease when ctx is done so another replica can
+	// take over without waiting for LeaseDuration.
+	ReleaseOnCancel bool
+
+	// OnStartedLeading runs in its own goroutine; ctx is cancelled when
+	// leadership is lost and the work must stop.
+	OnStartedLeading func(ctx context.Context)
+	// OnStoppedLeading is called whenever an election round ends, after
+	// leadership is lost and once more when ctx is done, even if this
+	// replica never led.
+	OnStoppedLeading func()
+	OnNewLeader      func(identity string)
+}
+
+type LeaderElector struct {
+	name     string
+	identity string
+	elector  *leaderelection.LeaderElector
+	watchdog *leaderelection.HealthzAdaptor
+	leading  atomic.Bool // set while the OnStartedLeading ctx is live
+}
+
+// NewLeaderElector prepares leader election on a coordination.k8s.io Lease.
+// Call Run to take part in the election.
+func (s *ClientImpl) NewLeaderElector(opts LeaderElectionOptions) (*LeaderElector, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("leader election lease name is required")
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = PodNamespace()
+	}
+	if opts.Identity == "" {
+		opts.Identity = PodName()
+	}
+	if opts.Identity == "" {
+		return nil, fmt.Errorf("leader election identity is empty, set %s or Identity", ENV_POD_NAME)
+	}
+	if opts.LeaseDuration == 0 {
+		opts.LeaseDuration = defaultLeaseDuration
+	}
+	if opts.RenewDeadline == 0 {
+		opts.RenewDeadline = defaultRenewDeadline
+	}
+	if opts.RetryPeriod == 0 {
+		opts.RetryPeriod = defaultRetryPeriod
+	}
+
+	e := &LeaderElector{
+		name:     opts.Name,
+		identity: opts.Identity,
+		watchdog: leaderelection.NewLeaderHealthzAdaptor(opts.RenewDeadline),
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Namespace: opts.Namespace, Name: opts.Name},
+		Client:     s.clients.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: opts.Identity},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		Name:            opts.Name,
+		LeaseDuration:   opts.LeaseDuration,
+		RenewDeadline:   opts.RenewDeadline,
+		RetryPeriod:     opts.RetryPeriod,
+		ReleaseOnCancel: opts.ReleaseOnCancel,
+		WatchDog:        e.watchdog,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				// client-go cancels ctx when renewal fails, even when it
+				// cannot clear the Lease record it last observed
+				e.leading.Store(true)
+				go func() {
+					<-ctx.Done()
+					e.leading.Store(false)
+				}()
+				if opts.OnStartedLeading != nil {
+					opts.OnStartedLeading(ctx)
+				}
+			},
+			OnStoppedLeading: func() {
+				if opts.OnStoppedLeading != nil {
+					opts.OnStoppedLeading()
+				}
+			},
+			OnNewLeader: func(identity string) {
+				if opts.OnNewLeader != nil {
+					opts.OnNewLeader(identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.elector = elector
+	e.watchdog.SetLeaderElection(elector)
+	return e, nil
+}
+
+// Run takes part in the election until ctx is done. A replica that loses
+// leadership becomes a candidate again.
+func (e *LeaderElector) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		e.elector.Run(ctx)
+	}
+}
+
+func (e *LeaderElector) Identity() string {
+	return e.identity
+}
+
+// IsLeader reports whether this replica holds the Lease and its leading ctx
+// has not been cancelled.
+func (e *LeaderElector) IsLeader() bool {
+	return e.leading.Load() && e.elector.IsLeader()
+}
+
+// Leader returns the identity of the last observed leader.
+func (e *LeaderElector) Leader() string {
+	return e.elector.GetLeader()
+}
+
+// Ready returns nil while this replica is the leader, for readiness probes.
+func (e *LeaderElector) Ready() error {
+	if !e.IsLeader() {
+		return fmt.Errorf("%s is not the leader of %s, current leader is %q", e.identity, e.name, e.Leader())
+	}
+	return nil
+}
+
+// Healthy returns an error when the leader failed to renew its Lease well
+// past the deadline, for liveness probes. Non-leaders are always healthy.
+func (e *LeaderElector) Healthy() error {
+	return e.watchdog.Check(nil)
+}
+
+// ReadinessHandler answers 200 while this replica is the leader and 503
+// otherwise.
+func (e *LeaderElector) ReadinessHandler() http.Handler {
+	return probeHandler(e.Ready)
+}
+
+// LivenessHandler answers 200 unless Healthy reports an error.
+func (e *LeaderElector) LivenessHandler() http.Handler {
+	return probeHandler(e.Healthy)
+}
+
+func probeHandler(check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+}
+
+// PodName returns the POD_NAME environment variable, usually set through the
+// downward API, falling back to the hostname which equals the pod name.
+func PodName() string {
+	if name := os.Getenv(ENV_POD_NAME); name != "" {
+		return name
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// PodNamespace returns the POD_NAMESPACE environment variable, falling back to
+// the service account namespace and then to default.
+func PodNamespace() string {
+	if namespace := os.Getenv(ENV_POD_NAMESPACE); namespace != "" {
+		return namespace
+	}
+	if data, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+		if namespace := strings.TrimSpace(string(data)); namespace != "" {
+			return namespace
+		}
+	}
+	return metav1.NamespaceDefault
+}
@@ -0,0 +1,319 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coordinationclientv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+)
+
+const LOCK_LEASE_PREFIX = "idpp2-lock-"
+
+const (
+	defaultLockLeaseDuration = 15 * time.Second
+	defaultLockRetryInterval = time.Second
+)
+
+type LockOptions struct {
+	Namespace string // namespace of the Lease objects, defaults to the pod namespace, see PodNamespace
+	Identity  string // prefix of the holder identity, defaults to the pod name, see PodName
+
+	// LeaseDuration is how long a holder that stopped renewing keeps the lock
+	// before it is considered stale and may be taken over. It is stored in
+	// whole seconds, must be at least 1s and defaults to 15s.
+	LeaseDuration time.Duration
+	// RenewDeadline is how long after its last successful renewal the holder
+	// gives the lock up and cancels its Context. It must be shorter than
+	// LeaseDuration so the holder stops before another one may take over, and
+	// defaults to two thirds of it.
+	RenewDeadline time.Duration
+	RenewInterval time.Duration // defaults to a quarter of RenewDeadline
+	RetryInterval time.Duration // wait between attempts while the lock is held elsewhere, defaults to 1s
+}
+
+type LockManager struct {
+	client *ClientImpl
+	opts   LockOptions
+}
+
+// NamedLock is a held lock. Its Context is cancelled when the lock is lost
+// or released, work protected by the lock must stop then.
+type NamedLock struct {
+	Name     string
+	Identity string
+
+	manager   *LockManager
+	leaseName string
+	lease     *coordinationv1.Lease
+	renewedAt time.Time // RenewTime last written to the Lease
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	err       error
+}
+
+// NewLockManager returns a manager for named mutexes backed by
+// coordination.k8s.io Leases named LOCK_LEASE_PREFIX + name.
+func (s *ClientImpl) NewLockManager(opts LockOptions) (*LockManager, error) {
+	if opts.Namespace == "" {
+		opts.Namespace = PodNamespace()
+	}
+	if opts.Identity == "" {
+		opts.Identity = PodName()
+	}
+	if opts.LeaseDuration == 0 {
+		opts.LeaseDuration = defaultLockLeaseDuration
+	}
+	if opts.LeaseDuration < time.Second {
+		return nil, fmt.Errorf("lock lease duration %v is shorter than 1s", opts.LeaseDuration)
+	}
+	opts.LeaseDuration = opts.LeaseDuration.Truncate(time.Second)
+	if opts.RenewDeadline == 0 {
+		opts.RenewDeadline = opts.LeaseDuration * 2 / 3
+	}
+	if opts.RenewInterval == 0 {
+		opts.RenewInterval = opts.RenewDeadline / 4
+	}
+	if opts.RetryInterval == 0 {
+		opts.RetryInterval = defaultLockRetryInterval
+	}
+	if opts.RenewDeadline >= opts.LeaseDuration {
+		return nil, fmt.Errorf("lock renew deadline %v must be shorter than lease duration %v", opts.RenewDeadline, opts.LeaseDuration)
+	}
+	if opts.RenewInterval <= 0 || opts.RenewInterval >= opts.RenewDeadline {
+		return nil, fmt.Errorf("lock renew interval %v must be positive and shorter than renew deadline %v", opts.RenewInterval, opts.RenewDeadline)
+	}
+	return &LockManager{client: s, opts: opts}, nil
+}
+
+// Lock blocks until the named lock is acquired or ctx is done. The lock is
+// renewed in the background; the returned lock's Context derives from ctx and
+// is cancelled once RenewDeadline passes without a successful renewal.
+func (m *LockManager) Lock(ctx context.Context, name string) (*NamedLock, error) {
+	l := m.newLock(name)
+	err := wait.PollImmediateUntilWithContext(ctx, m.opts.RetryInterval, func(ctx context.Context) (bool, error) {
+		return l.tryAcquire(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("lock %s: %v", name, err)
+	}
+	l.start(ctx)
+	return l, nil
+}
+
+// TryLock acquires the named lock if it is free or stale, without waiting.
+func (m *LockManager) TryLock(ctx context.Context, name string) (*NamedLock, bool, error) {
+	l := m.newLock(name)
+	ok, err := l.tryAcquire(ctx)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	l.start(ctx)
+	return l, true, nil
+}
+
+// WithLock runs fn while holding the named lock, e.g. to provision a project
+// namespace and its quota once. fn gets the lock's Context and the lock is
+// released when fn returns.
+func (m *LockManager) WithLock(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	l, err := m.Lock(ctx, name)
+	if err != nil {
+		return err
+	}
+	fnErr := fn(l.Context())
+	unlockErr := l.Unlock(context.Background())
+	if fnErr != nil {
+		return fnErr
+	}
+	return unlockErr
+}
+
+func (m *LockManager) newLock(name string) *NamedLock {
+	return &NamedLock{
+		Name:      name,
+		Identity:  fmt.Sprintf("%s_%s", m.opts.Identity, utilrand.String(8)),
+		manager:   m,
+		leaseName: LOCK_LEASE_PREFIX + name,
+	}
+}
+
+func (l *NamedLock) Context() context.Context {
+	return l.ctx
+}
+
+// Err returns why the lock was lost, or nil while it is held or after Unlock.
+func (l *NamedLock) Err() error {
+	select {
+	case <-l.done:
+		return l.err
+	default:
+		return nil
+	}
+}
+
+// Unlock stops renewing and deletes the Lease if this lock still holds it.
+func (l *NamedLock) Unlock(ctx context.Context) error {
+	l.cancel()
+	<-l.done
+	if l.err != nil {
+		return l.err
+	}
+
+	uid, resourceVersion := l.lease.UID, l.lease.ResourceVersion
+	opt := metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid, ResourceVersion: &resourceVersion}}
+	err := l.leases().Delete(ctx, l.leaseName, opt)
+	if apierrors.IsConflict(err) || apierrors.IsNotFound(err) {
+		return fmt.Errorf("lock %s was lost before unlock", l.Name)
+	}
+	return err
+}
+
+func (l *NamedLock) tryAcquire(ctx context.Context) (bool, error) {
+	now := metav1.NewMicroTime(time.Now())
+	duration := int32(l.manager.opts.LeaseDuration / time.Second)
+	l.renewedAt = now.Time
+
+	lease, err := l.leases().Get(ctx, l.leaseName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: l.leaseName, Namespace: l.manager.opts.Namespace},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &l.Identity,
+				LeaseDurationSeconds: &duration,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		opt := metav1.CreateOptions{FieldManager: FIELD_MANAGER}
+		created, err := l.leases().Create(ctx, lease, opt)
+		if apierrors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		l.lease = created
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if leaseHeld(lease, now.Time) {
+		return false, nil
+	}
+
+	transitions := int32(1)
+	if lease.Spec.LeaseTransitions != nil {
+		transitions = *lease.Spec.LeaseTransitions + 1
+	}
+	lease.Spec = coordinationv1.LeaseSpec{
+		HolderIdentity:       &l.Identity,
+		LeaseDurationSeconds: &duration,
+		AcquireTime:          &now,
+		RenewTime:            &now,
+		LeaseTransitions:     &transitions,
+	}
+	opt := metav1.UpdateOptions{FieldManager: FIELD_MANAGER}
+	updated, err := l.leases().Update(ctx, lease, opt)
+	if apierrors.IsConflict(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	l.lease = updated
+	return true, nil
+}
+
+func (l *NamedLock) start(ctx context.Context) {
+	l.ctx, l.cancel = context.WithCancel(ctx)
+	l.done = make(chan struct{})
+	go l.renew()
+}
+
+func (l *NamedLock) renew() {
+	defer close(l.done)
+	defer l.cancel()
+
+	deadline := time.NewTimer(time.Until(l.deadline()))
+	defer deadline.Stop()
+	ticker := time.NewTicker(l.manager.opts.RenewInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-deadline.C:
+			l.err = fmt.Errorf("lock %s expired, renew failed: %v", l.Name, lastErr)
+			return
+		case <-ticker.C:
+		}
+
+		lastErr = l.renewOnce()
+		switch {
+		case lastErr == nil:
+			if !deadline.Stop() {
+				<-deadline.C
+			}
+			deadline.Reset(time.Until(l.deadline()))
+		case apierrors.IsConflict(lastErr) || apierrors.IsNotFound(lastErr):
+			l.err = fmt.Errorf("lock %s was taken over: %v", l.Name, lastErr)
+			return
+		case !time.Now().Before(l.deadline()):
+			l.err = fmt.Errorf("lock %s expired, renew failed: %v", l.Name, lastErr)
+			return
+		}
+	}
+}
+
+// deadline is when the holder must consider the lock lost, measured from the
+// RenewTime it last wrote.
+func (l *NamedLock) deadline() time.Time {
+	return l.renewedAt.Add(l.manager.opts.RenewDeadline)
+}
+
+// renewOnce writes a new RenewTime. The request is bounded by the current
+// deadline so a slow API server cannot keep the lock alive past it.
+func (l *NamedLock) renewOnce() error {
+	ctx, cancel := context.WithDeadline(l.ctx, l.deadline())
+	defer cancel()
+
+	lease := l.lease.DeepCopy()
+	now := metav1.NewMicroTime(time.Now())
+	lease.Spec.RenewTime = &now
+	opt := metav1.UpdateOptions{FieldManager: FIELD_MANAGER}
+	updated, err := l.leases().Update(ctx, lease, opt)
+	if err != nil {
+		return err
+	}
+	l.lease = updated
+	l.renewedAt = now.Time
+	return nil
+}
+
+func (l *NamedLock) leases() coordinationclientv1.LeaseInterface {
+	return l.manager.client.clients.CoordinationV1().Leases(l.manager.opts.Namespace)
+}
+
+// leaseHeld reports whether the lease has a holder that renewed it within its
+// duration. Holders on other machines are compared by wall clock.
+func leaseHeld(lease *coordinationv1.Lease, now time.Time) bool {
+	spec := lease.Spec
+	if spec.HolderIdentity == nil || *spec.HolderIdentity == "" {
+		return false
+	}
+	if spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+		return false
+	}
+	expires := spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second)
+	return now.Before(expires)
+}
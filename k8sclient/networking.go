@@ -0,0 +1,151 @@
+package k8sclient
+
+import (
+	"context"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	configmetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	confignetworkingv1 "k8s.io/client-go/applyconfigurations/networking/v1"
+)
+
+const NETWORKPOLICY_PROJECT_ISOLATION = "idpp2-project-isolation"
+
+type IngressExposeOptions struct {
+	Host             string
+	Path             string                // defaults to "/"
+	PathType         networkingv1.PathType // defaults to Prefix
+	ServiceName      string
+	ServicePort      int32
+	IngressClassName string
+	TLSSecretName    string // enables TLS for Host when set
+	Annotations      map[string]string
+	Labels           K8sLabels
+}
+
+func (s *ClientImpl) ListIngress(ctx context.Context, namespace string, selector string) (*networkingv1.IngressList, error) {
+	opts := metav1.ListOptions{}
+	if selector != "" {
+		opts.LabelSelector = selector
+	}
+
+	return s.clients.NetworkingV1().Ingresses(namespace).List(ctx, opts)
+}
+
+func (s *ClientImpl) GetIngress(ctx context.Context, namespace string, name string) (*networkingv1.Ingress, error) {
+	opt := metav1.GetOptions{}
+	return s.clients.NetworkingV1().Ingresses(namespace).Get(ctx, name, opt)
+}
+
+func (s *ClientImpl) ApplyIngress(ctx context.Context, namespace string, config *confignetworkingv1.IngressApplyConfiguration) (*networkingv1.Ingress, error) {
+	opt := metav1.ApplyOptions{FieldManager: FIELD_MANAGER}
+	return s.clients.NetworkingV1().Ingresses(namespace).Apply(ctx, config, opt)
+}
+
+func (s *ClientImpl) DeleteIngress(ctx context.Context, namespace string, name string) error {
+	opt := metav1.DeleteOptions{} // == metav1.NewDeleteOptions(0)
+	return s.clients.NetworkingV1().Ingresses(namespace).Delete(ctx, name, opt)
+}
+
+func (s *ClientImpl) DeleteIngresses(ctx context.Context, namespace string, selector string) error {
+	deleteOpt := metav1.DeleteOptions{}
+	selectOpt := metav1.ListOptions{}
+	if selector != "" {
+		selectOpt.LabelSelector = selector
+	}
+	return s.clients.NetworkingV1().Ingresses(namespace).DeleteCollection(ctx, deleteOpt, selectOpt)
+}
+
+// ExposeService publishes a Service, such as a user's notebook or TF Serving
+// endpoint, under a hostname and path through an Ingress named name.
+func (s *ClientImpl) ExposeService(ctx context.Context, namespace string, name string, opts IngressExposeOptions) (*networkingv1.Ingress, error) {
+	path := opts.Path
+	if path == "" {
+		path = "/"
+	}
+	pathType := opts.PathType
+	if pathType == "" {
+		pathType = networkingv1.PathTypePrefix
+	}
+
+	backend := confignetworkingv1.IngressBackend().WithService(
+		confignetworkingv1.IngressServiceBackend().
+			WithName(opts.ServiceName).
+			WithPort(confignetworkingv1.ServiceBackendPort().WithNumber(opts.ServicePort)))
+
+	rule := confignetworkingv1.IngressRule().
+		WithHost(opts.Host).
+		WithHTTP(confignetworkingv1.HTTPIngressRuleValue().WithPaths(
+			confignetworkingv1.HTTPIngressPath().WithPath(path).WithPathType(pathType).WithBackend(backend)))
+
+	spec := confignetworkingv1.IngressSpec().WithRules(rule)
+	if opts.IngressClassName != "" {
+		spec.WithIngressClassName(opts.IngressClassName)
+	}
+	if opts.TLSSecretName != "" {
+		spec.WithTLS(confignetworkingv1.IngressTLS().WithHosts(opts.Host).WithSecretName(opts.TLSSecretName))
+	}
+
+	config := confignetworkingv1.Ingress(name, namespace).
+		WithLabels(opts.Labels).
+		WithAnnotations(opts.Annotations).
+		WithSpec(spec)
+
+	return s.ApplyIngress(ctx, namespace, config)
+}
+
+func (s *ClientImpl) ListNetworkPolicy(ctx context.Context, namespace string, selector string) (*networkingv1.NetworkPolicyList, error) {
+	opts := metav1.ListOptions{}
+	if selector != "" {
+		opts.LabelSelector = selector
+	}
+
+	return s.clients.NetworkingV1().NetworkPolicies(namespace).List(ctx, opts)
+}
+
+func (s *ClientImpl) GetNetworkPolicy(ctx context.Context, namespace string, name string) (*networkingv1.NetworkPolicy, error) {
+	opt := metav1.GetOptions{}
+	return s.clients.NetworkingV1().NetworkPolicies(namespace).Get(ctx, name, opt)
+}
+
+func (s *ClientImpl) ApplyNetworkPolicy(ctx context.Context, namespace string, config *confignetworkingv1.NetworkPolicyApplyConfiguration) (*networkingv1.NetworkPolicy, error) {
+	opt := metav1.ApplyOptions{FieldManager: FIELD_MANAGER}
+	return s.clients.NetworkingV1().NetworkPolicies(namespace).Apply(ctx, config, opt)
+}
+
+func (s *ClientImpl) DeleteNetworkPolicy(ctx context.Context, namespace string, name string) error {
+	opt := metav1.DeleteOptions{} // == metav1.NewDeleteOptions(0)
+	return s.clients.NetworkingV1().NetworkPolicies(namespace).Delete(ctx, name, opt)
+}
+
+func (s *ClientImpl) DeleteNetworkPolicies(ctx context.Context, namespace string, selector string) error {
+	deleteOpt := metav1.DeleteOptions{}
+	selectOpt := metav1.ListOptions{}
+	if selector != "" {
+		selectOpt.LabelSelector = selector
+	}
+	return s.clients.NetworkingV1().NetworkPolicies(namespace).DeleteCollection(ctx, deleteOpt, selectOpt)
+}
+
+// ApplyProjectNetworkPolicy isolates a project namespace: pods accept traffic
+// only from the same namespace and from the ingress controller pods in
+// ingressNamespace. ingressLabels narrows the controller pods and may be nil.
+func (s *ClientImpl) ApplyProjectNetworkPolicy(ctx context.Context, namespace string, ingressNamespace string, ingressLabels K8sLabels) (*networkingv1.NetworkPolicy, error) {
+	sameNamespace := confignetworkingv1.NetworkPolicyPeer().
+		WithPodSelector(configmetav1.LabelSelector())
+
+	ingressController := confignetworkingv1.NetworkPolicyPeer().
+		WithNamespaceSelector(configmetav1.LabelSelector().WithMatchLabels(map[string]string{LABEL_NAMESPACE_NAME: ingressNamespace}))
+	if len(ingressLabels) > 0 {
+		ingressController.WithPodSelector(configmetav1.LabelSelector().WithMatchLabels(ingressLabels))
+	}
+
+	config := confignetworkingv1.NetworkPolicy(NETWORKPOLICY_PROJECT_ISOLATION, namespace).
+		WithLabels(map[string]string{LABEL_ENVIRONMENT: AIBLAB_ENVIRONMENT}).
+		WithSpec(confignetworkingv1.NetworkPolicySpec().
+			WithPodSelector(configmetav1.LabelSelector()).
+			WithPolicyTypes(networkingv1.PolicyTypeIngress).
+			WithIngress(confignetworkingv1.NetworkPolicyIngressRule().WithFrom(sameNamespace, ingressController)))
+
+	return s.ApplyNetworkPolicy(ctx, namespace, config)
+}
@@ -0,0 +1,191 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	DRAIN_POD_SKIPPED  = "Skipped"
+	DRAIN_POD_EVICTING = "Evicting"
+	DRAIN_POD_BLOCKED  = "Blocked"
+	DRAIN_POD_EVICTED  = "Evicted"
+	DRAIN_POD_FAILED   = "Failed"
+)
+
+const annotationMirrorPod = "kubernetes.io/config.mirror"
+
+type DrainProgress struct {
+	Namespace string
+	Name      string
+	Status    string // one of the DRAIN_POD_* values
+	Message   string
+}
+
+type DrainOptions struct {
+	DeleteEmptyDirData bool   // evict pods using emptyDir volumes, losing their data
+	Force              bool   // evict pods not managed by a controller
+	GracePeriodSeconds *int64 // overrides the pods' own grace period when set
+	Timeout            time.Duration
+	RetryInterval      time.Duration // wait between evictions blocked by a PodDisruptionBudget
+	// Progress is called from one goroutine per pod and must be safe for concurrent use.
+	Progress func(DrainProgress)
+}
+
+func (s *ClientImpl) CordonNode(ctx context.Context, name string) (*corev1.Node, error) {
+	return s.setNodeUnschedulable(ctx, name, true)
+}
+
+func (s *ClientImpl) UncordonNode(ctx context.Context, name string) (*corev1.Node, error) {
+	return s.setNodeUnschedulable(ctx, name, false)
+}
+
+func (s *ClientImpl) setNodeUnschedulable(ctx context.Context, name string, unschedulable bool) (*corev1.Node, error) {
+	opts := metav1.PatchOptions{FieldManager: FIELD_MANAGER}
+
+	data := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	return s.clients.CoreV1().Nodes().Patch(ctx, name, types.StrategicMergePatchType, []byte(data), opts)
+}
+
+// DrainNode cordons the node and evicts its pods through the Eviction API so
+// PodDisruptionBudgets are honored. DaemonSet and mirror pods are skipped.
+// Evictions refused with 429 are retried until opts.Timeout expires.
+func (s *ClientImpl) DrainNode(ctx context.Context, name string, opts DrainOptions) error {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	progress := func(pod *corev1.Pod, status string, message string) {
+		if opts.Progress != nil {
+			opts.Progress(DrainProgress{Namespace: pod.Namespace, Name: pod.Name, Status: status, Message: message})
+		}
+	}
+
+	if _, err := s.CordonNode(ctx, name); err != nil {
+		return err
+	}
+
+	listOpt := metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String()}
+	pods, err := s.clients.CoreV1().Pods(metav1.NamespaceAll).List(ctx, listOpt)
+	if err != nil {
+		return err
+	}
+
+	var evict []*corev1.Pod
+	var errs []error
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if skip, reason := drainSkipPod(pod); skip {
+			progress(pod, DRAIN_POD_SKIPPED, reason)
+			continue
+		}
+		if err := drainCheckPod(pod, opts); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		evict = append(evict, pod)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("cannot drain node %s: %v", name, utilerrors.NewAggregate(errs))
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, pod := range evict {
+		wg.Add(1)
+		go func(pod *corev1.Pod) {
+			defer wg.Done()
+			err := s.drainPod(ctx, pod, opts, progress)
+			if err != nil {
+				progress(pod, DRAIN_POD_FAILED, err.Error())
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s/%s: %v", pod.Namespace, pod.Name, err))
+				mu.Unlock()
+				return
+			}
+			progress(pod, DRAIN_POD_EVICTED, "")
+		}(pod)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("drain node %s: %v", name, utilerrors.NewAggregate(errs))
+	}
+	return nil
+}
+
+func (s *ClientImpl) drainPod(ctx context.Context, pod *corev1.Pod, opts DrainOptions, progress func(*corev1.Pod, string, string)) error {
+	evictOpt := EvictOptions{
+		GracePeriodSeconds: opts.GracePeriodSeconds,
+		Retry:              true,
+		RetryInterval:      opts.RetryInterval,
+		OnBlocked: func(err error) {
+			progress(pod, DRAIN_POD_BLOCKED, err.Error())
+		},
+	}
+
+	progress(pod, DRAIN_POD_EVICTING, "")
+	if err := s.EvictPod(ctx, pod.Namespace, pod.Name, evictOpt); err != nil {
+		return err
+	}
+
+	return s.waitPodDeleted(ctx, pod)
+}
+
+func (s *ClientImpl) waitPodDeleted(ctx context.Context, pod *corev1.Pod) error {
+	return wait.PollImmediateUntilWithContext(ctx, time.Second, func(ctx context.Context) (bool, error) {
+		current, err := s.GetPod(ctx, pod.Namespace, pod.Name)
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return current.UID != pod.UID, nil
+	})
+}
+
+func drainSkipPod(pod *corev1.Pod) (bool, string) {
+	if _, ok := pod.Annotations[annotationMirrorPod]; ok {
+		return true, "mirror pod"
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "DaemonSet" {
+		return true, "managed by DaemonSet " + owner.Name
+	}
+	return false, ""
+}
+
+func drainCheckPod(pod *corev1.Pod, opts DrainOptions) error {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return nil
+	}
+
+	var problems []string
+	if !opts.Force && metav1.GetControllerOf(pod) == nil {
+		problems = append(problems, "not managed by a controller (use Force)")
+	}
+	if !opts.DeleteEmptyDirData {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.EmptyDir != nil {
+				problems = append(problems, "uses emptyDir volume "+volume.Name+" (use DeleteEmptyDirData)")
+				break
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("pod %s/%s %s", pod.Namespace, pod.Name, strings.Join(problems, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	configmetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	configpolicyv1 "k8s.io/client-go/applyconfigurations/policy/v1"
+)
+
+type WorkloadRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Replicas  int32
+}
+
+func (s *ClientImpl) ListPodDisruptionBudget(ctx context.Context, namespace string, selector string) (*policyv1.PodDisruptionBudgetList, error) {
+	opts := metav1.ListOptions{}
+	if selector != "" {
+		opts.LabelSelector = selector
+	}
+	return s.clients.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, opts)
+}
+
+func (s *ClientImpl) GetPodDisruptionBudget(ctx context.Context, namespace string, name string) (*policyv1.PodDisruptionBudget, error) {
+	opt := metav1.GetOptions{}
+	return s.clients.PolicyV1().PodDisruptionBudgets(namespace).Get(ctx, name, opt)
+}
+
+func (s *ClientImpl) ApplyPodDisruptionBudget(ctx context.Context, namespace string, config *configpolicyv1.PodDisruptionBudgetApplyConfiguration) (*policyv1.PodDisruptionBudget, error) {
+	opt := metav1.ApplyOptions{FieldManager: FIELD_MANAGER}
+	return s.clients.PolicyV1().PodDisruptionBudgets(namespace).Apply(ctx, config, opt)
+}
+
+func (s *ClientImpl) DeletePodDisruptionBudget(ctx context.Context, namespace string, name string) error {
+	opt := metav1.DeleteOptions{} // == metav1.NewDeleteOptions(0)
+	return s.clients.PolicyV1().PodDisruptionBudgets(namespace).Delete(ctx, name, opt)
+}
+
+func (s *ClientImpl) DeletePodDisruptionBudgets(ctx context.Context, namespace string, selector string) error {
+	deleteOpt := metav1.DeleteOptions{}
+	selectOpt := metav1.ListOptions{}
+	if selector != "" {
+		selectOpt.LabelSelector = selector
+	}
+	return s.clients.PolicyV1().PodDisruptionBudgets(namespace).DeleteCollection(ctx, deleteOpt, selectOpt)
+}
+
+// ApplyWorkloadPodDisruptionBudget applies a PDB named <name>-pdb that covers
+// the pods of a Deployment or StatefulSet. Without maxUnavailable the budget
+// follows the replica count: with several replicas maxUnavailable is 1, so
+// node maintenance proceeds one pod at a time; with a single replica
+// minAvailable is 1, so the only pod is never evicted and DrainNode blocks
+// until the workload is scaled up, moved by hand or the PDB is removed.
+func (s *ClientImpl) ApplyWorkloadPodDisruptionBudget(ctx context.Context, namespace string, kind string, name string, maxUnavailable *intstr.IntOrString) (*policyv1.PodDisruptionBudget, error) {
+	var selector *metav1.LabelSelector
+	var replicas *int32
+	switch kind {
+	case TYPEMETA_KIND_DEPLOYMENT:
+		deployment, err := s.GetDeployment(ctx, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		selector = deployment.Spec.Selector
+		replicas = deployment.Spec.Replicas
+	case TYPEMETA_KIND_STATEFULSET:
+		statefulSet, err := s.GetStatefulSet(ctx, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		selector = statefulSet.Spec.Selector
+		replicas = statefulSet.Spec.Replicas
+	default:
+		return nil, fmt.Errorf("unsupported workload kind %s", kind)
+	}
+
+	spec := configpolicyv1.PodDisruptionBudgetSpec().WithSelector(labelSelectorConfig(selector))
+	switch {
+	case maxUnavailable != nil:
+		spec.WithMaxUnavailable(*maxUnavailable)
+	case replicas == nil || *replicas <= 1:
+		spec.WithMinAvailable(intstr.FromInt(1))
+	default:
+		spec.WithMaxUnavailable(intstr.FromInt(1))
+	}
+
+	config := configpolicyv1.PodDisruptionBudget(fmt.Sprintf("%s-pdb", name), namespace).
+		WithLabels(map[string]string{LABEL_ENVIRONMENT: AIBLAB_ENVIRONMENT}).
+		WithSpec(spec)
+
+	return s.ApplyPodDisruptionBudget(ctx, namespace, config)
+}
+
+// WorkloadsWithoutPodDisruptionBudget reports the Deployments and
+// StatefulSets whose pods are not covered by any PDB.
+func (s *ClientImpl) WorkloadsWithoutPodDisruptionBudget(ctx context.Context, namespace string) ([]WorkloadRef, error) {
+	pdbs, err := s.ListPodDisruptionBudget(ctx, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+	deployments, err := s.ListDeployment(ctx, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+	statefulSets, err := s.ListStatefulSet(ctx, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+
+	covered := func(ns string, podLabels map[string]string) bool {
+		for _, pdb := range pdbs.Items {
+			if pdb.Namespace != ns || pdb.Spec.Selector == nil {
+				continue
+			}
+			// in policy/v1 an empty selector covers every pod of the namespace
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil {
+				continue
+			}
+			if selector.Matches(labels.Set(podLabels)) {
+				return true
+			}
+		}
+		return false
+	}
+	replicas := func(r *int32) int32 {
+		if r == nil {
+			return 1
+		}
+		return *r
+	}
+
+	var r []WorkloadRef
+	for _, deployment := range deployments.Items {
+		if !covered(deployment.Namespace, deployment.Spec.Template.Labels) {
+			r = append(r, WorkloadRef{Kind: TYPEMETA_KIND_DEPLOYMENT, Namespace: deployment.Namespace, Name: deployment.Name, Replicas: replicas(deployment.Spec.Replicas)})
+		}
+	}
+	for _, statefulSet := range statefulSets.Items {
+		if !covered(statefulSet.Namespace, statefulSet.Spec.Template.Labels) {
+			r = append(r, WorkloadRef{Kind: TYPEMETA_KIND_STATEFULSET, Namespace: statefulSet.Namespace, Name: statefulSet.Name, Replicas: replicas(statefulSet.Spec.Replicas)})
+		}
+	}
+	return r, nil
+}
+
+func labelSelectorConfig(selector *metav1.LabelSelector) *configmetav1.LabelSelectorApplyConfiguration {
+	config := configmetav1.LabelSelector()
+	if selector == nil {
+		return config
+	}
+	config.WithMatchLabels(selector.MatchLabels)
+	for _, expr := range selector.MatchExpressions {
+		config.WithMatchExpressions(configmetav1.LabelSelectorRequirement().
+			WithKey(expr.Key).
+			WithOperator(expr.Operator).
+			WithValues(expr.Values...))
+	}
+	return config
+}
@@ -0,0 +1,297 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	configrbacv1 "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	RBAC_ROLE_VIEWER = "viewer"
+	RBAC_ROLE_EDITOR = "editor"
+	RBAC_ROLE_ADMIN  = "admin"
+)
+
+// rbacProjectRoles maps the idpp2 role names to the built-in ClusterRoles.
+var rbacProjectRoles = map[string]string{
+	RBAC_ROLE_VIEWER: "view",
+	RBAC_ROLE_EDITOR: "edit",
+	RBAC_ROLE_ADMIN:  "admin",
+}
+
+// rbacNamespaceClusterRoles are the built-in ClusterRoles that grant access to
+// namespaced resources when bound cluster-wide. Other cluster bindings, such
+// as system:basic-user for system:authenticated, grant nothing in a namespace.
+var rbacNamespaceClusterRoles = map[string]bool{
+	"cluster-admin": true,
+	"admin":         true,
+	"edit":          true,
+	"view":          true,
+}
+
+type NamespaceAccess struct {
+	Kind        string // User, Group or ServiceAccount
+	Name        string
+	Namespace   string // set for ServiceAccount subjects
+	RoleKind    string // Role or ClusterRole
+	Role        string
+	Binding     string
+	ClusterWide bool // granted by a ClusterRoleBinding
+}
+
+func (s *ClientImpl) ListRole(ctx context.Context, namespace string, selector string) (*rbacv1.RoleList, error) {
+	opts := metav1.ListOptions{}
+	if selector != "" {
+		opts.LabelSelector = selector
+	}
+	return s.clients.RbacV1().Roles(namespace).List(ctx, opts)
+}
+
+func (s *ClientImpl) GetRole(ctx context.Context, namespace string, name string) (*rbacv1.Role, error) {
+	opt := metav1.GetOptions{}
+	return s.clients.RbacV1().Roles(namespace).Get(ctx, name, opt)
+}
+
+func (s *ClientImpl) ApplyRole(ctx context.Context, namespace string, config *configrbacv1.RoleApplyConfiguration) (*rbacv1.Role, error) {
+	opt := metav1.ApplyOptions{FieldManager: FIELD_MANAGER}
+	return s.clients.RbacV1().Roles(namespace).Apply(ctx, config, opt)
+}
+
+func (s *ClientImpl) DeleteRole(ctx context.Context, namespace string, name string) error {
+	opt := metav1.DeleteOptions{} // == metav1.NewDeleteOptions(0)
+	return s.clients.RbacV1().Roles(namespace).Delete(ctx, name, opt)
+}
+
+func (s *ClientImpl) DeleteRoles(ctx context.Context, namespace string, selector string) error {
+	deleteOpt := metav1.DeleteOptions{}
+	selectOpt := metav1.ListOptions{}
+	if selector != "" {
+		selectOpt.LabelSelector = selector
+	}
+	return s.clients.RbacV1().Roles(namespace).DeleteCollection(ctx, deleteOpt, selectOpt)
+}
+
+func (s *ClientImpl) ListRoleBinding(ctx context.Context, namespace string, selector string) (*rbacv1.RoleBindingList, error) {
+	opts := metav1.ListOptions{}
+	if selector != "" {
+		opts.LabelSelector = selector
+	}
+	return s.clients.RbacV1().RoleBindings(namespace).List(ctx, opts)
+}
+
+func (s *ClientImpl) GetRoleBinding(ctx context.Context, namespace string, name string) (*rbacv1.RoleBinding, error) {
+	opt := metav1.GetOptions{}
+	return s.clients.RbacV1().RoleBindings(namespace).Get(ctx, name, opt)
+}
+
+func (s *ClientImpl) ApplyRoleBinding(ctx context.Context, namespace string, config *configrbacv1.RoleBindingApplyConfiguration) (*rbacv1.RoleBinding, error) {
+	opt := metav1.ApplyOptions{FieldManager: FIELD_MANAGER}
+	return s.clients.RbacV1().RoleBindings(namespace).Apply(ctx, config, opt)
+}
+
+func (s *ClientImpl) DeleteRoleBinding(ctx context.Context, namespace string, name string) error {
+	opt := metav1.DeleteOptions{} // == metav1.NewDeleteOptions(0)
+	return s.clients.RbacV1().RoleBindings(namespace).Delete(ctx, name, opt)
+}
+
+func (s *ClientImpl) DeleteRoleBindings(ctx context.Context, namespace string, selector string) error {
+	deleteOpt := metav1.DeleteOptions{}
+	selectOpt := metav1.ListOptions{}
+	if selector != "" {
+		selectOpt.LabelSelector = selector
+	}
+	return s.clients.RbacV1().RoleBindings(namespace).DeleteCollection(ctx, deleteOpt, selectOpt)
+}
+
+func (s *ClientImpl) ListClusterRole(ctx context.Context, selector string) (*rbacv1.ClusterRoleList, error) {
+	opts := metav1.ListOptions{}
+	if selector != "" {
+		opts.LabelSelector = selector
+	}
+	return s.clients.RbacV1().ClusterRoles().List(ctx, opts)
+}
+
+func (s *ClientImpl) GetClusterRole(ctx context.Context, name string) (*rbacv1.ClusterRole, error) {
+	opt := metav1.GetOptions{}
+	return s.clients.RbacV1().ClusterRoles().Get(ctx, name, opt)
+}
+
+func (s *ClientImpl) ApplyClusterRole(ctx context.Context, config *configrbacv1.ClusterRoleApplyConfiguration) (*rbacv1.ClusterRole, error) {
+	opt := metav1.ApplyOptions{FieldManager: FIELD_MANAGER}
+	return s.clients.RbacV1().ClusterRoles().Apply(ctx, config, opt)
+}
+
+func (s *ClientImpl) DeleteClusterRole(ctx context.Context, name string) error {
+	opt := metav1.DeleteOptions{} // == metav1.NewDeleteOptions(0)
+	return s.clients.RbacV1().ClusterRoles().Delete(ctx, name, opt)
+}
+
+func (s *ClientImpl) ListClusterRoleBinding(ctx context.Context, selector string) (*rbacv1.ClusterRoleBindingList, error) {
+	opts := metav1.ListOptions{}
+	if selector != "" {
+		opts.LabelSelector = selector
+	}
+	return s.clients.RbacV1().ClusterRoleBindings().List(ctx, opts)
+}
+
+func (s *ClientImpl) GetClusterRoleBinding(ctx context.Context, name string) (*rbacv1.ClusterRoleBinding, error) {
+	opt := metav1.GetOptions{}
+	return s.clients.RbacV1().ClusterRoleBindings().Get(ctx, name, opt)
+}
+
+func (s *ClientImpl) ApplyClusterRoleBinding(ctx context.Context, config *configrbacv1.ClusterRoleBindingApplyConfiguration) (*rbacv1.ClusterRoleBinding, error) {
+	opt := metav1.ApplyOptions{FieldManager: FIELD_MANAGER}
+	return s.clients.RbacV1().ClusterRoleBindings().Apply(ctx, config, opt)
+}
+
+func (s *ClientImpl) DeleteClusterRoleBinding(ctx context.Context, name string) error {
+	opt := metav1.DeleteOptions{} // == metav1.NewDeleteOptions(0)
+	return s.clients.RbacV1().ClusterRoleBindings().Delete(ctx, name, opt)
+}
+
+// GrantNamespaceRole gives a User or Group one of the RBAC_ROLE_* roles in a
+// namespace. Each role is kept in a single RoleBinding named idpp2-<role>
+// whose subject list is updated in place.
+func (s *ClientImpl) GrantNamespaceRole(ctx context.Context, namespace string, subjectKind string, subjectName string, role string) (*rbacv1.RoleBinding, error) {
+	clusterRole, ok := rbacProjectRoles[role]
+	if !ok {
+		return nil, fmt.Errorf("unknown role %s", role)
+	}
+	if subjectKind != rbacv1.UserKind && subjectKind != rbacv1.GroupKind {
+		return nil, fmt.Errorf("unsupported subject kind %s", subjectKind)
+	}
+	subject := rbacv1.Subject{Kind: subjectKind, APIGroup: rbacv1.GroupName, Name: subjectName}
+	name := projectRoleBindingName(role)
+
+	var result *rbacv1.RoleBinding
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		binding, err := s.GetRoleBinding(ctx, namespace, name)
+		if apierrors.IsNotFound(err) {
+			binding = &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+					Labels:    map[string]string{LABEL_ENVIRONMENT: AIBLAB_ENVIRONMENT},
+				},
+				RoleRef:  rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: clusterRole},
+				Subjects: []rbacv1.Subject{subject},
+			}
+			result, err = s.clients.RbacV1().RoleBindings(namespace).Create(ctx, binding, metav1.CreateOptions{FieldManager: FIELD_MANAGER})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, existing := range binding.Subjects {
+			if existing.Kind == subject.Kind && existing.Name == subject.Name {
+				result = binding
+				return nil
+			}
+		}
+		binding.Subjects = append(binding.Subjects, subject)
+		result, err = s.clients.RbacV1().RoleBindings(namespace).Update(ctx, binding, metav1.UpdateOptions{FieldManager: FIELD_MANAGER})
+		return err
+	})
+	return result, err
+}
+
+// RevokeNamespaceRole removes a User or Group from a role granted by
+// GrantNamespaceRole. An empty role revokes every idpp2 role. RoleBindings
+// left without subjects are deleted.
+func (s *ClientImpl) RevokeNamespaceRole(ctx context.Context, namespace string, subjectKind string, subjectName string, role string) error {
+	roles := []string{role}
+	if role == "" {
+		roles = []string{RBAC_ROLE_VIEWER, RBAC_ROLE_EDITOR, RBAC_ROLE_ADMIN}
+	} else if _, ok := rbacProjectRoles[role]; !ok {
+		return fmt.Errorf("unknown role %s", role)
+	}
+
+	for _, r := range roles {
+		name := projectRoleBindingName(r)
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			binding, err := s.GetRoleBinding(ctx, namespace, name)
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			subjects := binding.Subjects[:0]
+			for _, existing := range binding.Subjects {
+				if existing.Kind != subjectKind || existing.Name != subjectName {
+					subjects = append(subjects, existing)
+				}
+			}
+			if len(subjects) == len(binding.Subjects) {
+				return nil
+			}
+			if len(subjects) == 0 {
+				opt := metav1.DeleteOptions{Preconditions: &metav1.Preconditions{ResourceVersion: &binding.ResourceVersion}}
+				return s.clients.RbacV1().RoleBindings(namespace).Delete(ctx, name, opt)
+			}
+			binding.Subjects = subjects
+			_, err = s.clients.RbacV1().RoleBindings(namespace).Update(ctx, binding, metav1.UpdateOptions{FieldManager: FIELD_MANAGER})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListNamespaceAccess lists every subject bound to a role in the namespace,
+// including subjects of ClusterRoleBindings to cluster-admin, admin, edit and
+// view, which apply to all namespaces.
+func (s *ClientImpl) ListNamespaceAccess(ctx context.Context, namespace string) ([]NamespaceAccess, error) {
+	bindings, err := s.ListRoleBinding(ctx, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+	clusterBindings, err := s.ListClusterRoleBinding(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var r []NamespaceAccess
+	for _, binding := range bindings.Items {
+		for _, subject := range binding.Subjects {
+			r = append(r, NamespaceAccess{
+				Kind:      subject.Kind,
+				Name:      subject.Name,
+				Namespace: subject.Namespace,
+				RoleKind:  binding.RoleRef.Kind,
+				Role:      binding.RoleRef.Name,
+				Binding:   binding.Name,
+			})
+		}
+	}
+	for _, binding := range clusterBindings.Items {
+		if binding.RoleRef.Kind != "ClusterRole" || !rbacNamespaceClusterRoles[binding.RoleRef.Name] {
+			continue
+		}
+		for _, subject := range binding.Subjects {
+			r = append(r, NamespaceAccess{
+				Kind:        subject.Kind,
+				Name:        subject.Name,
+				Namespace:   subject.Namespace,
+				RoleKind:    binding.RoleRef.Kind,
+				Role:        binding.RoleRef.Name,
+				Binding:     binding.Name,
+				ClusterWide: true,
+			})
+		}
+	}
+	return r, nil
+}
+
+func projectRoleBindingName(role string) string {
+	return fmt.Sprintf("%s-%s", AIBLAB_ENVIRONMENT, role)
+}
@@ -0,0 +1,72 @@
+package k8sclient
+
+import (
+	"context"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+)
+
+const (
+	EVENT_REASON_RESTARTED     = "Restarted"
+	EVENT_REASON_DELETED       = "Deleted"
+	EVENT_REASON_QUOTA_APPLIED = "QuotaApplied"
+	EVENT_ACTION_RESTART       = "Restart"
+	EVENT_ACTION_DELETE        = "Delete"
+	EVENT_ACTION_APPLY         = "Apply"
+	EVENT_REPORTING_CONTROLLER = "builder.aiblab.co.kr/" + FIELD_MANAGER
+)
+
+type eventRecorder struct {
+	mu       sync.RWMutex
+	recorder events.EventRecorder
+}
+
+// StartEventRecorder starts sending events/v1 Events recorded by this client,
+// both for its own actions and through RecordEvent, until ctx is done. Events
+// are dropped while the recorder is not running.
+func (s *ClientImpl) StartEventRecorder(ctx context.Context) {
+	broadcaster := events.NewBroadcaster(&events.EventSinkImpl{Interface: s.clients.EventsV1()})
+	broadcaster.StartRecordingToSink(ctx.Done())
+
+	s.eventRecorder.mu.Lock()
+	s.eventRecorder.recorder = broadcaster.NewRecorder(scheme.Scheme, EVENT_REPORTING_CONTROLLER)
+	s.eventRecorder.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.eventRecorder.mu.Lock()
+		s.eventRecorder.recorder = nil
+		s.eventRecorder.mu.Unlock()
+		broadcaster.Shutdown()
+	}()
+}
+
+// EventRecorder returns the running recorder, or nil when StartEventRecorder
+// has not been called.
+func (s *ClientImpl) EventRecorder() events.EventRecorder {
+	s.eventRecorder.mu.RLock()
+	defer s.eventRecorder.mu.RUnlock()
+	return s.eventRecorder.recorder
+}
+
+// RecordEvent records an event regarding the object, which may be a typed
+// object or a *corev1.ObjectReference. The note is a format string.
+func (s *ClientImpl) RecordEvent(regarding runtime.Object, eventType string, reason string, action string, note string, args ...interface{}) {
+	recorder := s.EventRecorder()
+	if recorder == nil || regarding == nil {
+		return
+	}
+	recorder.Eventf(regarding, nil, eventType, reason, action, note, args...)
+}
+
+func (s *ClientImpl) RecordNormalEvent(regarding runtime.Object, reason string, action string, note string, args ...interface{}) {
+	s.RecordEvent(regarding, corev1.EventTypeNormal, reason, action, note, args...)
+}
+
+func (s *ClientImpl) RecordWarningEvent(regarding runtime.Object, reason string, action string, note string, args ...interface{}) {
+	s.RecordEvent(regarding, corev1.EventTypeWarning, reason, action, note, args...)
+}
@@ -0,0 +1,209 @@
+package k8sclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	configv1 "k8s.io/client-go/applyconfigurations/core/v1"
+	configmetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+type TLSCertificateInfo struct {
+	Subject   string
+	Issuer    string
+	DNSNames  []string
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+func (s TLSCertificateInfo) ExpiresIn() time.Duration {
+	return time.Until(s.NotAfter)
+}
+
+func (s *ClientImpl) ApplySecret(ctx context.Context, namespace string, name string, secretType corev1.SecretType, data map[string][]byte, labels K8sLabels) (*corev1.Secret, error) {
+	kind := TYPEMETA_KIND_SECRET
+	ver := TYPEMETA_APIVERSION_V1
+
+	config := configv1.SecretApplyConfiguration{
+		TypeMetaApplyConfiguration:   configmetav1.TypeMetaApplyConfiguration{Kind: &kind, APIVersion: &ver},
+		ObjectMetaApplyConfiguration: &configmetav1.ObjectMetaApplyConfiguration{Namespace: &namespace, Name: &name, Labels: labels},
+		Data:                         data,
+		Type:                         &secretType,
+	}
+	opt := metav1.ApplyOptions{FieldManager: FIELD_MANAGER}
+
+	return s.clients.CoreV1().Secrets(namespace).Apply(ctx, &config, opt)
+}
+
+func (s *ClientImpl) ApplyRegistrySecret(ctx context.Context, namespace string, name string, server string, username string, password string, email string, labels K8sLabels) (*corev1.Secret, error) {
+	data, err := NewDockerConfigJSON(server, username, password, email)
+	if err != nil {
+		return nil, err
+	}
+	return s.ApplySecret(ctx, namespace, name, corev1.SecretTypeDockerConfigJson, data, labels)
+}
+
+func (s *ClientImpl) ApplyTLSSecret(ctx context.Context, namespace string, name string, certPEM []byte, keyPEM []byte, labels K8sLabels) (*corev1.Secret, *TLSCertificateInfo, error) {
+	data, info, err := NewTLSSecretData(certPEM, keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	secret, err := s.ApplySecret(ctx, namespace, name, corev1.SecretTypeTLS, data, labels)
+	if err != nil {
+		return nil, nil, err
+	}
+	return secret, info, nil
+}
+
+func (s *ClientImpl) ApplyOpaqueSecret(ctx context.Context, namespace string, name string, data map[string][]byte, labels K8sLabels) (*corev1.Secret, error) {
+	if err := validateDataKeys(data); err != nil {
+		return nil, err
+	}
+	return s.ApplySecret(ctx, namespace, name, corev1.SecretTypeOpaque, data, labels)
+}
+
+// NewDockerConfigJSON builds the data of a kubernetes.io/dockerconfigjson
+// secret for a single registry.
+func NewDockerConfigJSON(server string, username string, password string, email string) (map[string][]byte, error) {
+	if server == "" || username == "" {
+		return nil, fmt.Errorf("registry server and username are required")
+	}
+	type authEntry struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Email    string `json:"email,omitempty"`
+		Auth     string `json:"auth"`
+	}
+	config := map[string]map[string]authEntry{
+		"auths": {
+			server: {
+				Username: username,
+				Password: password,
+				Email:    email,
+				Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+			},
+		},
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{corev1.DockerConfigJsonKey: data}, nil
+}
+
+// NewTLSSecretData checks that the certificate and key belong together and
+// returns the data of a kubernetes.io/tls secret with the leaf certificate
+// details.
+func NewTLSSecretData(certPEM []byte, keyPEM []byte) (map[string][]byte, *TLSCertificateInfo, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid tls certificate and key: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid tls certificate: %v", err)
+	}
+
+	info := &TLSCertificateInfo{
+		Subject:   leaf.Subject.String(),
+		Issuer:    leaf.Issuer.String(),
+		DNSNames:  leaf.DNSNames,
+		NotBefore: leaf.NotBefore,
+		NotAfter:  leaf.NotAfter,
+	}
+	data := map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+	}
+	return data, info, nil
+}
+
+// DataFromFiles reads files into secret or configmap data the way
+// `kubectl create --from-file` does. Each source is a file, a directory whose
+// regular files are all read, or key=path to choose the key.
+func DataFromFiles(sources ...string) (map[string][]byte, error) {
+	data := make(map[string][]byte)
+	put := func(key string, file string) error {
+		if _, ok := data[key]; ok {
+			return fmt.Errorf("duplicate key %s", key)
+		}
+		value, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		data[key] = value
+		return nil
+	}
+
+	for _, source := range sources {
+		key, file := "", source
+		if i := strings.Index(source, "="); i >= 0 {
+			key, file = source[:i], source[i+1:]
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if key == "" {
+				key = filepath.Base(file)
+			}
+			if err := put(key, file); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if key != "" {
+			return nil, fmt.Errorf("cannot give a key to directory %s", file)
+		}
+		entries, err := os.ReadDir(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			if err := put(entry.Name(), filepath.Join(file, entry.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := validateDataKeys(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// DataFromMap converts string values to secret data.
+func DataFromMap(values map[string]string) map[string][]byte {
+	data := make(map[string][]byte, len(values))
+	for k, v := range values {
+		data[k] = []byte(v)
+	}
+	return data
+}
+
+func validateDataKeys(data map[string][]byte) error {
+	for key := range data {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			return fmt.Errorf("invalid key %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
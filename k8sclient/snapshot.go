@@ -0,0 +1,284 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	watch "k8s.io/apimachinery/pkg/watch"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+const ANNOTATION_DEFAULT_SNAPSHOTCLASS = "snapshot.storage.kubernetes.io/is-default-class"
+
+const volumeSnapshotPVCSourceField = "persistentVolumeClaimName"
+
+var (
+	volumeSnapshotGVR      = schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshots"}
+	volumeSnapshotClassGVR = schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshotclasses"}
+	pvcGVR                 = corev1.SchemeGroupVersion.WithResource("persistentvolumeclaims")
+)
+
+// usernameLabels are carried from a workspace PVC to its snapshots and to
+// PVCs restored from them.
+var usernameLabels = []string{LABEL_IDPP2_USERNAME, LABEL_JUPYTERUSERNAME}
+
+type VolumeSnapshot struct {
+	Namespace     string
+	Name          string
+	PVC           string
+	SnapshotClass string
+	ReadyToUse    bool
+	RestoreSize   *resource.Quantity
+	CreationTime  *time.Time // when the storage system took the snapshot
+	Error         string
+	Labels        map[string]string
+}
+
+type VolumeSnapshotClass struct {
+	Name           string
+	Driver         string
+	DeletionPolicy string
+	Default        bool
+}
+
+func (s *DynamicImpl) CreateVolumeSnapshot(ctx context.Context, namespace string, name string, pvcName string, className string) (*VolumeSnapshot, error) {
+	pvc, err := s.clients.Resource(pvcGVR).Namespace(namespace).Get(ctx, pvcName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{volumeSnapshotPVCSourceField: pvcName},
+	}
+	if className != "" {
+		spec["volumeSnapshotClassName"] = className
+	}
+	snapshot := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": TYPEMETA_APIVERSION_SNAPSHOT_V1,
+		"kind":       TYPEMETA_KIND_VOLUMESNAPSHOT,
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"spec":       spec,
+	}}
+	snapshot.SetLabels(copyUsernameLabels(pvc.GetLabels()))
+
+	created, err := s.clients.Resource(volumeSnapshotGVR).Namespace(namespace).Create(ctx, snapshot, metav1.CreateOptions{FieldManager: FIELD_MANAGER})
+	if err != nil {
+		return nil, err
+	}
+	return toVolumeSnapshot(created), nil
+}
+
+func (s *DynamicImpl) GetVolumeSnapshot(ctx context.Context, namespace string, name string) (*VolumeSnapshot, error) {
+	opt := metav1.GetOptions{}
+	snapshot, err := s.clients.Resource(volumeSnapshotGVR).Namespace(namespace).Get(ctx, name, opt)
+	if err != nil {
+		return nil, err
+	}
+	return toVolumeSnapshot(snapshot), nil
+}
+
+func (s *DynamicImpl) ListVolumeSnapshot(ctx context.Context, namespace string, selector string) ([]VolumeSnapshot, error) {
+	opts := metav1.ListOptions{}
+	if selector != "" {
+		opts.LabelSelector = selector
+	}
+	list, err := s.clients.Resource(volumeSnapshotGVR).Namespace(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r := make([]VolumeSnapshot, 0, len(list.Items))
+	for i := range list.Items {
+		r = append(r, *toVolumeSnapshot(&list.Items[i]))
+	}
+	return r, nil
+}
+
+// ListVolumeSnapshotForPVC lists the snapshots taken from one PVC.
+func (s *DynamicImpl) ListVolumeSnapshotForPVC(ctx context.Context, namespace string, pvcName string) ([]VolumeSnapshot, error) {
+	snapshots, err := s.ListVolumeSnapshot(ctx, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var r []VolumeSnapshot
+	for _, snapshot := range snapshots {
+		if snapshot.PVC == pvcName {
+			r = append(r, snapshot)
+		}
+	}
+	return r, nil
+}
+
+func (s *DynamicImpl) DeleteVolumeSnapshot(ctx context.Context, namespace string, name string) error {
+	opt := metav1.DeleteOptions{} // == metav1.NewDeleteOptions(0)
+	return s.clients.Resource(volumeSnapshotGVR).Namespace(namespace).Delete(ctx, name, opt)
+}
+
+// WaitVolumeSnapshotReady watches the snapshot until readyToUse is true. It
+// fails when the snapshot controller reports an error.
+func (s *DynamicImpl) WaitVolumeSnapshotReady(ctx context.Context, namespace string, name string) (*VolumeSnapshot, error) {
+	current, err := s.clients.Resource(volumeSnapshotGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if snapshot := toVolumeSnapshot(current); snapshot.ReadyToUse {
+		return snapshot, nil
+	}
+
+	opt := metav1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+		ResourceVersion: current.GetResourceVersion(),
+	}
+	w, err := s.clients.Resource(volumeSnapshotGVR).Namespace(namespace).Watch(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *VolumeSnapshot
+	_, err = watchtools.UntilWithoutRetry(ctx, w, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("volume snapshot %s/%s deleted", namespace, name)
+		}
+		obj, ok := event.Object.(*unstructured.Unstructured)
+		if !ok {
+			return false, nil
+		}
+		result = toVolumeSnapshot(obj)
+		if result.Error != "" {
+			return false, fmt.Errorf("volume snapshot %s/%s: %s", namespace, name, result.Error)
+		}
+		return result.ReadyToUse, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *DynamicImpl) ListVolumeSnapshotClass(ctx context.Context) ([]VolumeSnapshotClass, error) {
+	list, err := s.clients.Resource(volumeSnapshotClassGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	r := make([]VolumeSnapshotClass, 0, len(list.Items))
+	for _, item := range list.Items {
+		driver, _, _ := unstructured.NestedString(item.Object, "driver")
+		policy, _, _ := unstructured.NestedString(item.Object, "deletionPolicy")
+		r = append(r, VolumeSnapshotClass{
+			Name:           item.GetName(),
+			Driver:         driver,
+			DeletionPolicy: policy,
+			Default:        item.GetAnnotations()[ANNOTATION_DEFAULT_SNAPSHOTCLASS] == "true",
+		})
+	}
+	return r, nil
+}
+
+// RestoreVolumeSnapshot creates a PVC named pvcName from a ready snapshot. The
+// storage class and access modes follow the source PVC when it still exists,
+// and the username labels of the snapshot are kept.
+func (s *DynamicImpl) RestoreVolumeSnapshot(ctx context.Context, namespace string, snapshotName string, pvcName string) (*corev1.PersistentVolumeClaim, error) {
+	snapshot, err := s.GetVolumeSnapshot(ctx, namespace, snapshotName)
+	if err != nil {
+		return nil, err
+	}
+	if !snapshot.ReadyToUse {
+		return nil, fmt.Errorf("volume snapshot %s/%s is not ready to use", namespace, snapshotName)
+	}
+
+	claim := &corev1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{Kind: TYPEMETA_KIND_PVC, APIVersion: TYPEMETA_APIVERSION_V1},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pvcName,
+			Namespace: namespace,
+			Labels:    copyUsernameLabels(snapshot.Labels),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			DataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: &volumeSnapshotGVR.Group,
+				Kind:     TYPEMETA_KIND_VOLUMESNAPSHOT,
+				Name:     snapshotName,
+			},
+		},
+	}
+
+	size := resource.Quantity{}
+	if snapshot.RestoreSize != nil {
+		size = snapshot.RestoreSize.DeepCopy()
+	}
+	if source, err := s.clients.Resource(pvcGVR).Namespace(namespace).Get(ctx, snapshot.PVC, metav1.GetOptions{}); err == nil {
+		sourcePVC := &corev1.PersistentVolumeClaim{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(source.Object, sourcePVC); err != nil {
+			return nil, err
+		}
+		claim.Spec.StorageClassName = sourcePVC.Spec.StorageClassName
+		claim.Spec.AccessModes = sourcePVC.Spec.AccessModes
+		if request := sourcePVC.Spec.Resources.Requests[corev1.ResourceStorage]; request.Cmp(size) > 0 {
+			size = request
+		}
+	}
+	if size.IsZero() {
+		return nil, fmt.Errorf("volume snapshot %s/%s has no restore size", namespace, snapshotName)
+	}
+	claim.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: size}
+
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(claim)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.clients.Resource(pvcGVR).Namespace(namespace).Create(ctx, &unstructured.Unstructured{Object: data}, metav1.CreateOptions{FieldManager: FIELD_MANAGER})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &corev1.PersistentVolumeClaim{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(created.Object, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func toVolumeSnapshot(obj *unstructured.Unstructured) *VolumeSnapshot {
+	r := &VolumeSnapshot{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Labels:    obj.GetLabels(),
+	}
+	r.PVC, _, _ = unstructured.NestedString(obj.Object, "spec", "source", volumeSnapshotPVCSourceField)
+	r.SnapshotClass, _, _ = unstructured.NestedString(obj.Object, "spec", "volumeSnapshotClassName")
+	r.ReadyToUse, _, _ = unstructured.NestedBool(obj.Object, "status", "readyToUse")
+	r.Error, _, _ = unstructured.NestedString(obj.Object, "status", "error", "message")
+
+	if size, ok, _ := unstructured.NestedString(obj.Object, "status", "restoreSize"); ok {
+		if q, err := resource.ParseQuantity(size); err == nil {
+			r.RestoreSize = &q
+		}
+	}
+	if created, ok, _ := unstructured.NestedString(obj.Object, "status", "creationTime"); ok {
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			r.CreationTime = &t
+		}
+	}
+	return r
+}
+
+func copyUsernameLabels(labels map[string]string) map[string]string {
+	r := make(map[string]string)
+	for _, key := range usernameLabels {
+		if value, ok := labels[key]; ok {
+			r[key] = value
+		}
+	}
+	return r
+}
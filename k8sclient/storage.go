@@ -0,0 +1,132 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+const (
+	ANNOTATION_DEFAULT_STORAGECLASS      = "storageclass.kubernetes.io/is-default-class"
+	ANNOTATION_BETA_DEFAULT_STORAGECLASS = "storageclass.beta.kubernetes.io/is-default-class"
+)
+
+func IsDefaultStorageClass(class *storagev1.StorageClass) bool {
+	return class.Annotations[ANNOTATION_DEFAULT_STORAGECLASS] == "true" ||
+		class.Annotations[ANNOTATION_BETA_DEFAULT_STORAGECLASS] == "true"
+}
+
+func StorageClassAllowsExpansion(class *storagev1.StorageClass) bool {
+	return class.AllowVolumeExpansion != nil && *class.AllowVolumeExpansion
+}
+
+// DefaultStorageClass returns the class marked as default. When several are
+// marked, the newest one wins, as the API server does when defaulting PVCs.
+func (s *ClientImpl) DefaultStorageClass(ctx context.Context) (*storagev1.StorageClass, error) {
+	classes, err := s.ListStorageClass(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var r *storagev1.StorageClass
+	for i := range classes.Items {
+		class := &classes.Items[i]
+		if !IsDefaultStorageClass(class) {
+			continue
+		}
+		if r == nil || r.CreationTimestamp.Before(&class.CreationTimestamp) {
+			r = class
+		}
+	}
+	if r == nil {
+		return nil, apierrors.NewNotFound(storagev1.Resource("storageclasses"), "default")
+	}
+	return r, nil
+}
+
+// ExpandPersistentVolumeClaim raises the storage request of a bound PVC and
+// waits, including through FileSystemResizePending, until the new capacity is
+// reported in its status. Bound the wait with ctx: a filesystem resize only
+// completes once a pod mounts the volume.
+func (s *ClientImpl) ExpandPersistentVolumeClaim(ctx context.Context, namespace string, name string, size resource.Quantity) (*corev1.PersistentVolumeClaim, error) {
+	pvc, err := s.GetPersistentVolumeClaim(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if pvc.Status.Phase != corev1.ClaimBound {
+		return nil, fmt.Errorf("pvc %s/%s is %s, only bound claims can be expanded", namespace, name, pvc.Status.Phase)
+	}
+	if current := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; size.Cmp(current) <= 0 {
+		return nil, fmt.Errorf("pvc %s/%s requests %s, new size %s must be larger", namespace, name, current.String(), size.String())
+	}
+
+	if pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName != "" {
+		class, err := s.GetStorageClass(ctx, *pvc.Spec.StorageClassName)
+		if err != nil {
+			return nil, err
+		}
+		if !StorageClassAllowsExpansion(class) {
+			return nil, fmt.Errorf("storage class %s does not allow volume expansion", class.Name)
+		}
+	}
+
+	opts := metav1.PatchOptions{FieldManager: FIELD_MANAGER}
+	data := fmt.Sprintf(`{"spec":{"resources":{"requests":{"storage":"%s"}}}}`, size.String())
+	patched, err := s.clients.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(data), opts)
+	if err != nil {
+		return nil, err
+	}
+	if pvcCapacityReached(patched, size) {
+		return patched, nil
+	}
+
+	// resizing may wait for a pod to mount the volume, UntilWithSync survives
+	// the server closing the watch in the meantime
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+			return s.clients.CoreV1().PersistentVolumeClaims(namespace).List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return s.clients.CoreV1().PersistentVolumeClaims(namespace).Watch(ctx, options)
+		},
+	}
+
+	event, err := watchtools.UntilWithSync(ctx, lw, &corev1.PersistentVolumeClaim{}, nil, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("pvc %s/%s deleted", namespace, name)
+		}
+		pvc, ok := event.Object.(*corev1.PersistentVolumeClaim)
+		return ok && pvcCapacityReached(pvc, size), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event.Object.(*corev1.PersistentVolumeClaim), nil
+}
+
+func pvcCapacityReached(pvc *corev1.PersistentVolumeClaim, size resource.Quantity) bool {
+	capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]
+	if !ok || capacity.Cmp(size) < 0 {
+		return false
+	}
+	for _, cond := range pvc.Status.Conditions {
+		if (cond.Type == corev1.PersistentVolumeClaimResizing || cond.Type == corev1.PersistentVolumeClaimFileSystemResizePending) && cond.Status == corev1.ConditionTrue {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,329 @@
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const ANNOTATION_PVC_LAST_MOUNTED = "aiblab.co.kr/last-mounted"
+
+// eventReasonVolumeAttached is recorded on a pod by the attach/detach
+// controller, its note names the PersistentVolume.
+const eventReasonVolumeAttached = "SuccessfulAttachVolume"
+
+const (
+	VOLUME_FINDING_RELEASED = "Released"
+	VOLUME_FINDING_FAILED   = "Failed"
+	VOLUME_FINDING_UNBOUND  = "Unbound"
+	VOLUME_FINDING_UNUSED   = "Unused"
+	// VOLUME_FINDING_LAST_USE_UNKNOWN is a bound PVC that is not mounted, is
+	// older than UnusedFor and has no recorded mount, so it may be in use
+	// occasionally.
+	VOLUME_FINDING_LAST_USE_UNKNOWN = "LastUseUnknown"
+)
+
+type StorageAuditOptions struct {
+	// UnusedFor reports bound PVCs no pod has mounted for at least this long
+	// and unbound PVCs pending for at least this long. Zero reports every PVC
+	// that is not mounted right now.
+	UnusedFor time.Duration
+	// RecordMounts stamps mounted PVCs with ANNOTATION_PVC_LAST_MOUNTED so
+	// later audits know when they were last in use.
+	RecordMounts bool
+}
+
+type VolumeFinding struct {
+	Kind      string // TYPEMETA_KIND_PV or TYPEMETA_KIND_PVC
+	Namespace string
+	Name      string
+	// UID and ResourceVersion at audit time, CleanupStorage only deletes the
+	// volume while both are unchanged.
+	UID             types.UID
+	ResourceVersion string
+	Reason          string // one of the VOLUME_FINDING_* values
+	StorageClass    string
+	Capacity        resource.Quantity
+	Username        string
+	LastUsed        time.Time // last known mount, zero when unknown
+}
+
+type StorageAudit struct {
+	Findings []VolumeFinding
+	// WastedCapacity is held by released, failed and unused volumes, volumes
+	// whose last use is unknown are not counted.
+	WastedCapacity resource.Quantity
+}
+
+// AuditStorage finds released or failed PersistentVolumes, unbound PVCs and
+// PVCs no pod has mounted for opts.UnusedFor. The last mount is taken from
+// ANNOTATION_PVC_LAST_MOUNTED and recent attach events. PVCs of a
+// WaitForFirstConsumer class waiting for their first pod are not reported.
+// An empty namespace audits the whole cluster; otherwise only PVs claimed
+// from that namespace are included.
+func (s *ClientImpl) AuditStorage(ctx context.Context, namespace string, opts StorageAuditOptions) (*StorageAudit, error) {
+	pvs, err := s.ListPersistentVolume(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	pvcs, err := s.ListPersistentVolumeClaim(ctx, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+	pods, err := s.listNonTerminatedPods(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	classes, err := s.ListStorageClass(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	waitForConsumer := make(map[string]bool)
+	for _, class := range classes.Items {
+		if class.VolumeBindingMode != nil && *class.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
+			waitForConsumer[class.Name] = true
+		}
+	}
+	attached, err := s.volumeAttachTimes(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	mounted := make(map[string]bool)
+	for _, pod := range pods.Items {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				mounted[pod.Namespace+"/"+volume.PersistentVolumeClaim.ClaimName] = true
+			}
+		}
+	}
+
+	audit := &StorageAudit{}
+	now := time.Now()
+
+	for _, pv := range pvs.Items {
+		if namespace != "" && (pv.Spec.ClaimRef == nil || pv.Spec.ClaimRef.Namespace != namespace) {
+			continue
+		}
+		reason := ""
+		switch pv.Status.Phase {
+		case corev1.VolumeReleased:
+			reason = VOLUME_FINDING_RELEASED
+		case corev1.VolumeFailed:
+			reason = VOLUME_FINDING_FAILED
+		default:
+			continue
+		}
+		finding := VolumeFinding{
+			Kind:            TYPEMETA_KIND_PV,
+			Name:            pv.Name,
+			UID:             pv.UID,
+			ResourceVersion: pv.ResourceVersion,
+			Reason:          reason,
+			StorageClass:    pv.Spec.StorageClassName,
+			Capacity:        pv.Spec.Capacity[corev1.ResourceStorage].DeepCopy(),
+			Username:        pv.Labels[LABEL_IDPP2_USERNAME],
+		}
+		if pv.Spec.ClaimRef != nil {
+			finding.Namespace = pv.Spec.ClaimRef.Namespace
+		}
+		audit.WastedCapacity.Add(finding.Capacity)
+		audit.Findings = append(audit.Findings, finding)
+	}
+
+	for _, pvc := range pvcs.Items {
+		finding := VolumeFinding{
+			Kind:            TYPEMETA_KIND_PVC,
+			Namespace:       pvc.Namespace,
+			Name:            pvc.Name,
+			UID:             pvc.UID,
+			ResourceVersion: pvc.ResourceVersion,
+			Username:        pvc.Labels[LABEL_IDPP2_USERNAME],
+			LastUsed:        pvcLastMounted(&pvc, attached[pvc.Spec.VolumeName]),
+		}
+		if pvc.Spec.StorageClassName != nil {
+			finding.StorageClass = *pvc.Spec.StorageClassName
+		}
+		age := now.Sub(pvc.CreationTimestamp.Time)
+
+		if pvc.Status.Phase != corev1.ClaimBound {
+			if age < opts.UnusedFor || mounted[pvc.Namespace+"/"+pvc.Name] {
+				continue
+			}
+			if pvc.Status.Phase == corev1.ClaimPending && waitForConsumer[finding.StorageClass] {
+				continue
+			}
+			finding.Reason = VOLUME_FINDING_UNBOUND
+			finding.Capacity = pvc.Spec.Resources.Requests[corev1.ResourceStorage].DeepCopy()
+			audit.Findings = append(audit.Findings, finding)
+			continue
+		}
+
+		if mounted[pvc.Namespace+"/"+pvc.Name] {
+			if opts.RecordMounts {
+				if err := s.recordPVCMount(ctx, pvc.Namespace, pvc.Name, now); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if age < opts.UnusedFor {
+			continue
+		}
+		if finding.LastUsed.IsZero() {
+			finding.Reason = VOLUME_FINDING_LAST_USE_UNKNOWN
+			finding.Capacity = pvc.Status.Capacity[corev1.ResourceStorage].DeepCopy()
+			audit.Findings = append(audit.Findings, finding)
+			continue
+		}
+		if now.Sub(finding.LastUsed) < opts.UnusedFor {
+			continue
+		}
+		finding.Reason = VOLUME_FINDING_UNUSED
+		finding.Capacity = pvc.Status.Capacity[corev1.ResourceStorage].DeepCopy()
+		audit.WastedCapacity.Add(finding.Capacity)
+		audit.Findings = append(audit.Findings, finding)
+	}
+
+	return audit, nil
+}
+
+// CleanupStorage deletes the volumes of an audit whose Reason is one of
+// reasons and returns one line per action; no reasons deletes nothing.
+// Volumes changed or recreated since the audit and PVCs a pod references now
+// are skipped. With
+// dryRun the deletions are sent as server-side dry runs, so the result shows
+// what would happen without changing anything.
+func (s *ClientImpl) CleanupStorage(ctx context.Context, audit *StorageAudit, reasons []string, dryRun bool) ([]string, error) {
+	selected := make(map[string]bool)
+	for _, reason := range reasons {
+		selected[reason] = true
+	}
+
+	prefix := ""
+	if dryRun {
+		prefix = "(dry run) "
+	}
+
+	// pods referencing PVCs, listed once per namespace when first needed
+	podsByClaim := make(map[string]map[string]string)
+	claimedBy := func(namespace string, name string) (string, error) {
+		claims, ok := podsByClaim[namespace]
+		if !ok {
+			pods, err := s.listNonTerminatedPods(ctx, namespace)
+			if err != nil {
+				return "", err
+			}
+			claims = make(map[string]string)
+			for _, pod := range pods.Items {
+				for _, volume := range pod.Spec.Volumes {
+					if volume.PersistentVolumeClaim != nil {
+						claims[volume.PersistentVolumeClaim.ClaimName] = pod.Name
+					}
+				}
+			}
+			podsByClaim[namespace] = claims
+		}
+		return claims[name], nil
+	}
+
+	var actions []string
+	for _, finding := range audit.Findings {
+		if !selected[finding.Reason] {
+			continue
+		}
+
+		uid, resourceVersion := finding.UID, finding.ResourceVersion
+		opt := metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid, ResourceVersion: &resourceVersion}}
+		if dryRun {
+			opt.DryRun = []string{metav1.DryRunAll}
+		}
+
+		var err error
+		switch finding.Kind {
+		case TYPEMETA_KIND_PV:
+			err = s.clients.CoreV1().PersistentVolumes().Delete(ctx, finding.Name, opt)
+		default:
+			pod, podErr := claimedBy(finding.Namespace, finding.Name)
+			if podErr != nil {
+				return actions, podErr
+			}
+			if pod != "" {
+				actions = append(actions, fmt.Sprintf("%sskipped %s %s/%s, used by pod %s", prefix, finding.Kind, finding.Namespace, finding.Name, pod))
+				continue
+			}
+			err = s.clients.CoreV1().PersistentVolumeClaims(finding.Namespace).Delete(ctx, finding.Name, opt)
+		}
+		if apierrors.IsConflict(err) || apierrors.IsNotFound(err) {
+			actions = append(actions, fmt.Sprintf("%sskipped %s %s/%s, changed since the audit", prefix, finding.Kind, finding.Namespace, finding.Name))
+			continue
+		}
+		if err != nil {
+			return actions, err
+		}
+		actions = append(actions, fmt.Sprintf("%sdeleted %s %s/%s (%s, %s)", prefix, finding.Kind, finding.Namespace, finding.Name, finding.Reason, finding.Capacity.String()))
+	}
+	return actions, nil
+}
+
+func (s *ClientImpl) recordPVCMount(ctx context.Context, namespace string, name string, at time.Time) error {
+	opts := metav1.PatchOptions{FieldManager: FIELD_MANAGER}
+
+	data := fmt.Sprintf(`{"metadata":{"annotations":{"%s":"%s"}}}`, ANNOTATION_PVC_LAST_MOUNTED, at.UTC().Format(time.RFC3339))
+	_, err := s.clients.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, name, types.MergePatchType, []byte(data), opts)
+	return err
+}
+
+// volumeAttachTimes returns the latest attach event per PersistentVolume
+// name. Events expire after an hour by default, so this only covers recent
+// use.
+func (s *ClientImpl) volumeAttachTimes(ctx context.Context, namespace string) (map[string]time.Time, error) {
+	opts := metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("reason", eventReasonVolumeAttached).String()}
+	events, err := s.clients.EventsV1().Events(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r := make(map[string]time.Time)
+	for i := range events.Items {
+		event := &events.Items[i]
+		// e.g. AttachVolume.Attach succeeded for volume "pvc-1234"
+		start := strings.Index(event.Note, `volume "`)
+		if start < 0 {
+			continue
+		}
+		name := event.Note[start+len(`volume "`):]
+		if end := strings.Index(name, `"`); end >= 0 {
+			name = name[:end]
+		}
+		if name == "" {
+			continue
+		}
+		if seen := eventLastSeen(event); seen.After(r[name]) {
+			r[name] = seen
+		}
+	}
+	return r, nil
+}
+
+// pvcLastMounted returns the later of the recorded mount annotation and the
+// last attach event, zero when neither is known.
+func pvcLastMounted(pvc *corev1.PersistentVolumeClaim, attached time.Time) time.Time {
+	last := attached
+	if value, ok := pvc.Annotations[ANNOTATION_PVC_LAST_MOUNTED]; ok {
+		if t, err := time.Parse(time.RFC3339, value); err == nil && t.After(last) {
+			last = t
+		}
+	}
+	return last
+}